@@ -0,0 +1,394 @@
+package tly
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// =====================
+// Account Backup
+// =====================
+
+// BackupVersion is the archive format version written by WriteBackup.
+const BackupVersion = 1
+
+// AccountBackup is a snapshot of a T.LY account.
+type AccountBackup struct {
+	Version    int          `json:"version"`
+	CreatedAt  string       `json:"created_at"`
+	Tags       []Tag        `json:"tags"`
+	Pixels     []Pixel      `json:"pixels"`
+	UTMPresets []UTMPreset  `json:"utm_presets"`
+	OneLinks   []OneLink    `json:"onelinks"`
+	Links      []BackupLink `json:"links"`
+	// Warnings lists data the backup could not capture.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// BackupLink is a short link as stored in a backup. Passwords are never
+// returned by the API and cannot be backed up.
+//
+// The API has no endpoint that reads a link's QR code options, so they are
+// only captured when the list endpoint includes them. QRCodeOptionsMissing
+// marks links whose options were not returned and so are not in the backup.
+type BackupLink struct {
+	ShortLink
+	QRCodeOptions        *QRCodeOptions `json:"qr_code_options,omitempty"`
+	QRCodeOptionsMissing bool           `json:"qr_code_options_missing,omitempty"`
+}
+
+// BackupAccount reads links, tags, pixels, UTM presets and OneLinks from the account.
+func (c *Client) BackupAccount() (*AccountBackup, error) {
+	backup := &AccountBackup{
+		Version:   BackupVersion,
+		CreatedAt: formatAPIDateTime(time.Now()),
+	}
+
+	var err error
+	if backup.Tags, err = c.ListTags(); err != nil {
+		return nil, fmt.Errorf("backup tags: %v", err)
+	}
+	if backup.Pixels, err = c.ListPixels(); err != nil {
+		return nil, fmt.Errorf("backup pixels: %v", err)
+	}
+	if backup.UTMPresets, err = c.ListUTMPresets(); err != nil {
+		return nil, fmt.Errorf("backup utm presets: %v", err)
+	}
+	for page := 1; ; page++ {
+		result, err := c.ListOneLinks(page)
+		if err != nil {
+			return nil, fmt.Errorf("backup onelinks: %v", err)
+		}
+		backup.OneLinks = append(backup.OneLinks, result.Data...)
+		if len(result.Data) == 0 || result.CurrentPage >= result.LastPage {
+			break
+		}
+	}
+	missingQR := 0
+	for page := 1; ; page++ {
+		var result struct {
+			CurrentPage int               `json:"current_page"`
+			LastPage    int               `json:"last_page"`
+			Data        []json.RawMessage `json:"data"`
+		}
+		query := ListShortLinksOptions{Page: page}.query()
+		if err := c.doRequest(http.MethodGet, "/api/v1/link/list", query, nil, &result); err != nil {
+			return nil, fmt.Errorf("backup links: %v", err)
+		}
+		for _, raw := range result.Data {
+			link, err := decodeBackupLink(raw)
+			if err != nil {
+				return nil, fmt.Errorf("backup links: %v", err)
+			}
+			if link.QRCodeOptionsMissing {
+				missingQR++
+			}
+			backup.Links = append(backup.Links, link)
+		}
+		if len(result.Data) == 0 || result.CurrentPage >= result.LastPage {
+			break
+		}
+	}
+	if missingQR > 0 {
+		backup.Warnings = append(backup.Warnings, fmt.Sprintf("qr code options were not returned for %d links and were not backed up", missingQR))
+	}
+	return backup, nil
+}
+
+// decodeBackupLink decodes one link from the list endpoint and records
+// whether the response included its QR code options.
+func decodeBackupLink(raw json.RawMessage) (BackupLink, error) {
+	var link BackupLink
+	if err := json.Unmarshal(raw, &link); err != nil {
+		return link, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return link, err
+	}
+	_, captured := fields["qr_code_options"]
+	link.QRCodeOptionsMissing = !captured
+	return link, nil
+}
+
+// WriteBackup writes backup to w as JSON.
+func WriteBackup(w io.Writer, backup *AccountBackup) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(backup)
+}
+
+// ReadBackup reads a backup written by WriteBackup.
+func ReadBackup(r io.Reader) (*AccountBackup, error) {
+	var backup AccountBackup
+	if err := json.NewDecoder(r).Decode(&backup); err != nil {
+		return nil, fmt.Errorf("unable to decode backup: %v", err)
+	}
+	if backup.Version < 1 || backup.Version > BackupVersion {
+		return nil, fmt.Errorf("unsupported backup version %d", backup.Version)
+	}
+	return &backup, nil
+}
+
+// Restore actions reported in RestoreItem.Action.
+const (
+	RestoreCreated     = "created"
+	RestoreSkipped     = "skipped"
+	RestoreFailed      = "failed"
+	RestoreUnsupported = "unsupported"
+)
+
+// RestoreOptions controls RestoreAccount.
+type RestoreOptions struct {
+	// DryRun reports what would be restored without changing the account.
+	DryRun bool
+}
+
+// RestoreItem is the outcome for one resource in the backup.
+type RestoreItem struct {
+	Kind   string
+	Name   string
+	Action string
+	Reason string
+}
+
+// RestoreReport lists the outcome for every resource in the backup.
+type RestoreReport struct {
+	DryRun bool
+	Items  []RestoreItem
+}
+
+// Count returns how many items ended with action.
+func (r *RestoreReport) Count(action string) int {
+	n := 0
+	for _, item := range r.Items {
+		if item.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *RestoreReport) add(kind, name, action, reason string) {
+	r.Items = append(r.Items, RestoreItem{Kind: kind, Name: name, Action: action, Reason: reason})
+}
+
+// RestoreAccount recreates the resources in backup on the client's account.
+// Tags, pixels and UTM presets that already exist (by name, or by type and
+// pixel ID for pixels) are skipped and reused, and tag and pixel IDs on links
+// are remapped to the target account. Links whose short URL already exists
+// are skipped. OneLinks are reported as unsupported because the API has no
+// endpoint to create them.
+func (c *Client) RestoreAccount(backup *AccountBackup, options RestoreOptions) (*RestoreReport, error) {
+	// Backed-up values were accepted by the API once; do not re-check them
+	// against client-side rules.
+	target := c.WithContext(c.requestContext())
+	target.SkipValidation = true
+	report := &RestoreReport{DryRun: options.DryRun}
+
+	tagIDs, err := target.restoreTags(backup.Tags, options, report)
+	if err != nil {
+		return report, err
+	}
+	pixelIDs, err := target.restorePixels(backup.Pixels, options, report)
+	if err != nil {
+		return report, err
+	}
+	if err := target.restoreUTMPresets(backup.UTMPresets, options, report); err != nil {
+		return report, err
+	}
+	for _, onelink := range backup.OneLinks {
+		report.add("onelink", onelink.ShortURL, RestoreUnsupported, "the API cannot create OneLinks")
+	}
+	for _, link := range backup.Links {
+		target.restoreLink(link, tagIDs, pixelIDs, options, report)
+	}
+	return report, nil
+}
+
+func (c *Client) restoreTags(tags []Tag, options RestoreOptions, report *RestoreReport) (map[int]int, error) {
+	existing, err := c.ListTags()
+	if err != nil {
+		return nil, err
+	}
+	byName := map[string]int{}
+	for _, tag := range existing {
+		byName[strings.ToLower(tag.Tag)] = tag.ID
+	}
+
+	ids := map[int]int{}
+	for _, tag := range tags {
+		if id, ok := byName[strings.ToLower(tag.Tag)]; ok {
+			ids[tag.ID] = id
+			report.add("tag", tag.Tag, RestoreSkipped, "already exists")
+			continue
+		}
+		if options.DryRun {
+			// Map to the old ID as a placeholder so links using this tag
+			// are not reported as dropping it; dry runs create no links.
+			ids[tag.ID] = tag.ID
+			report.add("tag", tag.Tag, RestoreCreated, "")
+			continue
+		}
+		created, err := c.CreateTag(tag.Tag)
+		if err != nil {
+			report.add("tag", tag.Tag, RestoreFailed, err.Error())
+			continue
+		}
+		ids[tag.ID] = created.ID
+		report.add("tag", tag.Tag, RestoreCreated, "")
+	}
+	return ids, nil
+}
+
+func (c *Client) restorePixels(pixels []Pixel, options RestoreOptions, report *RestoreReport) (map[int]int, error) {
+	existing, err := c.ListPixels()
+	if err != nil {
+		return nil, err
+	}
+	byKey := map[string]int{}
+	for _, pixel := range existing {
+		byKey[pixel.PixelType+"|"+pixel.PixelID] = pixel.ID
+	}
+
+	ids := map[int]int{}
+	for _, pixel := range pixels {
+		if id, ok := byKey[pixel.PixelType+"|"+pixel.PixelID]; ok {
+			ids[pixel.ID] = id
+			report.add("pixel", pixel.Name, RestoreSkipped, "already exists")
+			continue
+		}
+		if options.DryRun {
+			// Map to the old ID as a placeholder so links using this pixel
+			// are not reported as dropping it; dry runs create no links.
+			ids[pixel.ID] = pixel.ID
+			report.add("pixel", pixel.Name, RestoreCreated, "")
+			continue
+		}
+		created, err := c.CreatePixel(PixelCreateRequest{
+			Name:      pixel.Name,
+			PixelID:   pixel.PixelID,
+			PixelType: pixel.PixelType,
+		})
+		if err != nil {
+			report.add("pixel", pixel.Name, RestoreFailed, err.Error())
+			continue
+		}
+		ids[pixel.ID] = created.ID
+		report.add("pixel", pixel.Name, RestoreCreated, "")
+	}
+	return ids, nil
+}
+
+func (c *Client) restoreUTMPresets(presets []UTMPreset, options RestoreOptions, report *RestoreReport) error {
+	existing, err := c.ListUTMPresets()
+	if err != nil {
+		return err
+	}
+	names := map[string]bool{}
+	for _, preset := range existing {
+		names[strings.ToLower(preset.Name)] = true
+	}
+
+	for _, preset := range presets {
+		if names[strings.ToLower(preset.Name)] {
+			report.add("utm_preset", preset.Name, RestoreSkipped, "already exists")
+			continue
+		}
+		if options.DryRun {
+			report.add("utm_preset", preset.Name, RestoreCreated, "")
+			continue
+		}
+		_, err := c.CreateUTMPreset(UTMPresetRequest{
+			Name:     preset.Name,
+			Source:   preset.Source,
+			Medium:   preset.Medium,
+			Campaign: preset.Campaign,
+			Content:  preset.Content,
+			Term:     preset.Term,
+		})
+		if err != nil {
+			report.add("utm_preset", preset.Name, RestoreFailed, err.Error())
+			continue
+		}
+		report.add("utm_preset", preset.Name, RestoreCreated, "")
+	}
+	return nil
+}
+
+func (c *Client) restoreLink(link BackupLink, tagIDs, pixelIDs map[int]int, options RestoreOptions, report *RestoreReport) {
+	_, err := c.GetShortLink(link.ShortURL)
+	if err == nil {
+		report.add("link", link.ShortURL, RestoreSkipped, "already exists")
+		return
+	}
+	if apiErr, ok := err.(*APIError); !ok || apiErr.StatusCode != http.StatusNotFound {
+		report.add("link", link.ShortURL, RestoreFailed, err.Error())
+		return
+	}
+
+	reqData := ShortLinkCreateRequest{
+		LongURL:     link.LongURL,
+		Domain:      link.Domain,
+		Description: link.Description,
+		Meta:        link.Meta,
+	}
+	if link.ShortID != "" {
+		shortID := link.ShortID
+		reqData.ShortID = &shortID
+	}
+	if link.PublicStats {
+		publicStats := true
+		reqData.PublicStats = &publicStats
+	}
+	if datetime, ok := link.ExpireAtDatetime.(string); ok && datetime != "" {
+		reqData.ExpireAtDatetime = &datetime
+	}
+	if views, ok := link.ExpireAtViews.(float64); ok && views > 0 {
+		n := int(views)
+		reqData.ExpireAtViews = &n
+	}
+	var unmapped []string
+	for _, id := range link.TagIDs() {
+		if mapped, ok := tagIDs[id]; ok {
+			reqData.Tags = append(reqData.Tags, mapped)
+		} else {
+			unmapped = append(unmapped, fmt.Sprintf("tag %d", id))
+		}
+	}
+	for _, id := range link.PixelIDs() {
+		if mapped, ok := pixelIDs[id]; ok {
+			reqData.Pixels = append(reqData.Pixels, mapped)
+		} else {
+			unmapped = append(unmapped, fmt.Sprintf("pixel %d", id))
+		}
+	}
+
+	var notes []string
+	if len(unmapped) > 0 {
+		notes = append(notes, "dropped "+strings.Join(unmapped, ", "))
+	}
+	if link.QRCodeOptionsMissing {
+		notes = append(notes, "qr code options were not captured in the backup")
+	}
+
+	if options.DryRun {
+		report.add("link", link.ShortURL, RestoreCreated, strings.Join(notes, "; "))
+		return
+	}
+	created, err := c.CreateShortLink(reqData)
+	if err != nil {
+		report.add("link", link.ShortURL, RestoreFailed, err.Error())
+		return
+	}
+	if link.QRCodeOptions != nil && !link.QRCodeOptions.IsZero() {
+		if _, err := c.UpdateQRCode(link.QRCodeOptions.UpdateRequest(created.ShortURL)); err != nil {
+			notes = append(notes, "qr code options: "+err.Error())
+		}
+	}
+	report.add("link", link.ShortURL, RestoreCreated, strings.Join(notes, "; "))
+}
@@ -0,0 +1,175 @@
+package tly
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// =====================
+// Batch Execution
+// =====================
+
+// ErrBatchAborted is reported for items that were not run because the batch
+// reached BatchOptions.MaxFailures.
+var ErrBatchAborted = errors.New("batch aborted after too many failures")
+
+// BatchOperation runs one operation for a single batch input. The client it
+// receives is bound to the batch context.
+type BatchOperation func(c *Client, input interface{}) (interface{}, error)
+
+// BatchOptions controls how RunBatch executes a batch.
+type BatchOptions struct {
+	// Concurrency is the number of workers. Defaults to 4.
+	Concurrency int
+	// MaxFailures stops dispatching new items once this many have failed.
+	// Zero means never stop early.
+	MaxFailures int
+	// Progress, if set, is called after each item completes with the number
+	// of items done so far and the total. Calls are never concurrent.
+	Progress func(done, total int)
+}
+
+// BatchResult is the outcome of one batch input.
+type BatchResult struct {
+	Index  int
+	Input  interface{}
+	Output interface{}
+	Err    error
+}
+
+// RunBatch runs op over inputs with a pool of workers and returns one result
+// per input, in input order. Requests made by op go through the client's
+// RateLimiter and are bound to ctx. Items that were never started are marked
+// with ctx.Err() or ErrBatchAborted, which is also returned as the error.
+func (c *Client) RunBatch(ctx context.Context, inputs []interface{}, op BatchOperation, options BatchOptions) ([]BatchResult, error) {
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+	bound := c.WithContext(ctx)
+
+	results := make([]BatchResult, len(inputs))
+	for i, input := range inputs {
+		results[i] = BatchResult{Index: i, Input: input}
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		done     int
+		failures int
+		aborted  bool
+	)
+	stop := make(chan struct{})
+	indexes := make(chan int)
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				output, err := op(bound, inputs[i])
+				results[i].Output = output
+				results[i].Err = err
+
+				mu.Lock()
+				done++
+				if err != nil {
+					failures++
+					if options.MaxFailures > 0 && failures >= options.MaxFailures && !aborted {
+						aborted = true
+						close(stop)
+					}
+				}
+				if options.Progress != nil {
+					options.Progress(done, len(inputs))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	next := 0
+dispatch:
+	for ; next < len(inputs); next++ {
+		select {
+		case indexes <- next:
+		case <-stop:
+			break dispatch
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	var batchErr error
+	if aborted {
+		batchErr = ErrBatchAborted
+	} else if ctx.Err() != nil {
+		batchErr = ctx.Err()
+	}
+	for i := next; i < len(inputs); i++ {
+		results[i].Err = batchErr
+	}
+	return results, batchErr
+}
+
+// StringInputs converts a list of strings, such as short URLs, into batch inputs.
+func StringInputs(values []string) []interface{} {
+	inputs := make([]interface{}, len(values))
+	for i, v := range values {
+		inputs[i] = v
+	}
+	return inputs
+}
+
+func batchString(input interface{}) (string, error) {
+	value, ok := input.(string)
+	if !ok {
+		return "", fmt.Errorf("batch input must be a string, got %T", input)
+	}
+	return value, nil
+}
+
+// BatchGetShortLink is a BatchOperation that calls GetShortLink with a short URL input.
+func BatchGetShortLink(c *Client, input interface{}) (interface{}, error) {
+	shortURL, err := batchString(input)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetShortLink(shortURL)
+}
+
+// BatchDeleteShortLink is a BatchOperation that calls DeleteShortLink with a short URL input.
+func BatchDeleteShortLink(c *Client, input interface{}) (interface{}, error) {
+	shortURL, err := batchString(input)
+	if err != nil {
+		return nil, err
+	}
+	return nil, c.DeleteShortLink(shortURL)
+}
+
+// BatchGetStats is a BatchOperation that calls GetStatsWithRange with either a
+// short URL or a StatsRequest input.
+func BatchGetStats(c *Client, input interface{}) (interface{}, error) {
+	switch v := input.(type) {
+	case string:
+		return c.GetStats(v)
+	case StatsRequest:
+		return c.GetStatsWithRange(v)
+	default:
+		return nil, fmt.Errorf("batch input must be a string or StatsRequest, got %T", input)
+	}
+}
+
+// BatchUpdateShortLink is a BatchOperation that calls UpdateShortLink with a
+// ShortLinkUpdateRequest input.
+func BatchUpdateShortLink(c *Client, input interface{}) (interface{}, error) {
+	reqData, ok := input.(ShortLinkUpdateRequest)
+	if !ok {
+		return nil, fmt.Errorf("batch input must be a ShortLinkUpdateRequest, got %T", input)
+	}
+	return c.UpdateShortLink(reqData)
+}
@@ -0,0 +1,141 @@
+package tly
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+)
+
+// =====================
+// Chunked Bulk Shorten
+// =====================
+
+// DefaultBulkChunkSize is the number of links sent per bulk request when
+// BulkShortenChunkOptions.ChunkSize is not set.
+const DefaultBulkChunkSize = 100
+
+const defaultConcurrency = 4
+
+// BulkShortenChunkOptions controls how BulkShortenLinksChunked splits and sends links.
+type BulkShortenChunkOptions struct {
+	// ChunkSize is the number of links per request. Defaults to DefaultBulkChunkSize.
+	ChunkSize int
+	// Concurrency is the number of chunks sent at once. Defaults to 4.
+	Concurrency int
+	// Progress, if set, is called after each chunk completes with the number
+	// of links processed so far and the total. Calls are never concurrent.
+	Progress func(done, total int)
+}
+
+// BulkShortenResult is the outcome for one input link of a chunked bulk shorten.
+type BulkShortenResult struct {
+	// Index is the position of the link in BulkShortenRequest.Links.
+	Index int
+	// Link is the input entry.
+	Link interface{}
+	// ShortLink is set when the API returned per-link data for the chunk.
+	ShortLink *ShortLink
+	// Response is the raw payload of the request that carried this link.
+	Response string
+	// Err is the error of the request that carried this link, if any.
+	Err error
+}
+
+// BulkShortenLinksChunked splits reqData.Links into chunks and sends each as
+// its own bulk request, a few at a time. Links must be a slice (for example
+// []BulkShortenLink or []string). Requests go through the client's
+// RateLimiter when one is set. Results are returned in input order; a failed
+// chunk marks each of its links with the chunk's error.
+func (c *Client) BulkShortenLinksChunked(reqData BulkShortenRequest, options BulkShortenChunkOptions) ([]BulkShortenResult, error) {
+	links := reflect.ValueOf(reqData.Links)
+	if links.Kind() != reflect.Slice && links.Kind() != reflect.Array {
+		return nil, fmt.Errorf("bulk shorten links must be a slice, got %T", reqData.Links)
+	}
+	chunkSize := options.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultBulkChunkSize
+	}
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	total := links.Len()
+	results := make([]BulkShortenResult, total)
+	for i := range results {
+		results[i] = BulkShortenResult{Index: i, Link: links.Index(i).Interface()}
+	}
+
+	starts := make(chan int)
+	var wg sync.WaitGroup
+	var progressMu sync.Mutex
+	done := 0
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for start := range starts {
+				end := start + chunkSize
+				if end > total {
+					end = total
+				}
+				chunk := reqData
+				chunk.Links = links.Slice(start, end).Interface()
+				c.sendBulkShortenChunk(chunk, results[start:end])
+
+				if options.Progress != nil {
+					progressMu.Lock()
+					done += end - start
+					options.Progress(done, total)
+					progressMu.Unlock()
+				}
+			}
+		}()
+	}
+	for start := 0; start < total; start += chunkSize {
+		starts <- start
+	}
+	close(starts)
+	wg.Wait()
+
+	return results, nil
+}
+
+func (c *Client) sendBulkShortenChunk(chunk BulkShortenRequest, results []BulkShortenResult) {
+	data, err := c.doRequestRaw(http.MethodPost, "/api/v1/link/bulk", nil, chunk)
+	if err != nil {
+		for i := range results {
+			results[i].Err = err
+		}
+		return
+	}
+
+	created, ok := decodeShortLinkList(data)
+	perLink := ok && len(created) == len(results)
+	for i := range results {
+		results[i].Response = string(data)
+		if perLink {
+			link := created[i]
+			results[i].ShortLink = &link
+		}
+	}
+}
+
+// decodeShortLinkList decodes a payload that is either a list of short links
+// or an object wrapping one in "data".
+func decodeShortLinkList(data []byte) ([]ShortLink, bool) {
+	var links []ShortLink
+	if err := json.Unmarshal(data, &links); err == nil {
+		return links, true
+	}
+
+	var wrapped struct {
+		Data []ShortLink `json:"data"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err == nil && wrapped.Data != nil {
+		return wrapped.Data, true
+	}
+	return nil, false
+}
@@ -0,0 +1,107 @@
+package tly
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// =====================
+// Bulk Delete
+// =====================
+
+// LinkPredicate decides whether a link matches. It receives the client so
+// predicates can make API calls that honour the caller's context.
+type LinkPredicate func(c *Client, link ShortLink) (bool, error)
+
+// CreatedBefore matches links created before t.
+func CreatedBefore(t time.Time) LinkPredicate {
+	return func(c *Client, link ShortLink) (bool, error) {
+		created, err := parseAPIDateTime(link.CreatedAt)
+		if err != nil {
+			return false, fmt.Errorf("link %s: %v", link.ShortURL, err)
+		}
+		return created.Before(t), nil
+	}
+}
+
+// ZeroClicks matches links that have never been clicked. It calls GetStats
+// for every link it is asked about.
+func ZeroClicks(c *Client, link ShortLink) (bool, error) {
+	stats, err := c.GetStats(link.ShortURL)
+	if err != nil {
+		return false, err
+	}
+	return stats.Clicks == 0, nil
+}
+
+func matchLink(c *Client, link ShortLink, predicates []LinkPredicate) (bool, error) {
+	for _, predicate := range predicates {
+		ok, err := predicate(c, link)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// BulkDeleteOptions selects the links removed by BulkDeleteShortLinks.
+type BulkDeleteOptions struct {
+	// Filter is passed to the list endpoint.
+	Filter ListShortLinksOptions
+	// Predicates must all match for a link to be deleted.
+	Predicates []LinkPredicate
+	// DryRun reports the matching links without deleting them.
+	DryRun bool
+	// Concurrency and MaxFailures are passed to RunBatch.
+	Concurrency int
+	MaxFailures int
+}
+
+// BulkDeleteResult is the outcome for one matching link.
+type BulkDeleteResult struct {
+	Link    ShortLink
+	Deleted bool
+	Err     error
+}
+
+// BulkDeleteShortLinks lists every link matching options.Filter, checks the
+// predicates and deletes the links that match. All links are listed before
+// any is deleted so pagination is not disturbed. The results contain the
+// matching links and any link whose predicates could not be evaluated.
+func (c *Client) BulkDeleteShortLinks(ctx context.Context, options BulkDeleteOptions) ([]BulkDeleteResult, error) {
+	links, err := c.WithContext(ctx).ListAllShortLinks(options.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	inputs := make([]interface{}, len(links))
+	for i, link := range links {
+		inputs[i] = link
+	}
+	batch, batchErr := c.RunBatch(ctx, inputs, func(c *Client, input interface{}) (interface{}, error) {
+		link := input.(ShortLink)
+		matched, err := matchLink(c, link, options.Predicates)
+		if err != nil || !matched || options.DryRun {
+			return matched, err
+		}
+		return true, c.DeleteShortLink(link.ShortURL)
+	}, BatchOptions{
+		Concurrency: options.Concurrency,
+		MaxFailures: options.MaxFailures,
+	})
+
+	var results []BulkDeleteResult
+	for _, item := range batch {
+		matched, _ := item.Output.(bool)
+		if !matched && item.Err == nil {
+			continue
+		}
+		results = append(results, BulkDeleteResult{
+			Link:    item.Input.(ShortLink),
+			Deleted: matched && item.Err == nil && !options.DryRun,
+			Err:     item.Err,
+		})
+	}
+	return results, batchErr
+}
@@ -0,0 +1,231 @@
+package tly
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// =====================
+// Resumable Bulk Jobs
+// =====================
+
+// BulkJobItem is one create or update in a bulk job. Exactly one of Create
+// and Update must be set.
+type BulkJobItem struct {
+	// Key identifies the item across runs. It defaults to the item's index,
+	// which is only stable if the input order never changes.
+	Key    string
+	Create *ShortLinkCreateRequest
+	Update *ShortLinkUpdateRequest
+}
+
+// BulkJobOptions controls RunBulkJob.
+type BulkJobOptions struct {
+	// CheckpointPath is the file completed items are recorded in. It is
+	// created if missing and appended to as items complete.
+	CheckpointPath string
+	// Concurrency, MaxFailures and Progress are passed to RunBatch.
+	Concurrency int
+	MaxFailures int
+	Progress    func(done, total int)
+}
+
+// BulkJobItemResult is the outcome of one bulk job item.
+type BulkJobItemResult struct {
+	Key      string
+	ShortURL string
+	Err      error
+}
+
+// BulkJobReport summarises a bulk job run. Skipped holds items completed by
+// an earlier run, as recorded in the checkpoint file.
+type BulkJobReport struct {
+	Succeeded []BulkJobItemResult
+	Failed    []BulkJobItemResult
+	Skipped   []BulkJobItemResult
+}
+
+// checkpointStarted marks a create that was sent but not yet confirmed.
+const checkpointStarted = "started"
+
+type checkpointEntry struct {
+	Key         string `json:"key"`
+	State       string `json:"state,omitempty"`
+	ShortURL    string `json:"short_url,omitempty"`
+	CompletedAt string `json:"completed_at,omitempty"`
+}
+
+// RunBulkJob creates or updates links and records each success in a
+// checkpoint file as soon as it completes. Re-running the job with the same
+// items and checkpoint skips completed items, so a job that was interrupted
+// resumes where it stopped. Failed items are not recorded and are retried on
+// the next run.
+//
+// A create is also recorded as started before it is sent. If the process dies
+// after the API created the link but before the success was recorded, the
+// next run sees the started entry and goes through GetOrCreateShortLink, so
+// the existing link is reused instead of created again.
+func (c *Client) RunBulkJob(ctx context.Context, items []BulkJobItem, options BulkJobOptions) (*BulkJobReport, error) {
+	if options.CheckpointPath == "" {
+		return nil, fmt.Errorf("bulk job checkpoint path is required")
+	}
+	keys := make([]string, len(items))
+	seen := map[string]bool{}
+	for i, item := range items {
+		if (item.Create == nil) == (item.Update == nil) {
+			return nil, fmt.Errorf("bulk job item %d must set exactly one of Create and Update", i)
+		}
+		keys[i] = item.Key
+		if keys[i] == "" {
+			keys[i] = strconv.Itoa(i)
+		}
+		if seen[keys[i]] {
+			return nil, fmt.Errorf("duplicate bulk job key %q", keys[i])
+		}
+		seen[keys[i]] = true
+	}
+
+	completed, started, err := readCheckpoint(options.CheckpointPath)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(options.CheckpointPath, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if err := terminateLastLine(file); err != nil {
+		return nil, err
+	}
+
+	report := &BulkJobReport{}
+	var pending []interface{}
+	var pendingKeys []string
+	for i, item := range items {
+		if entry, ok := completed[keys[i]]; ok {
+			report.Skipped = append(report.Skipped, BulkJobItemResult{Key: keys[i], ShortURL: entry.ShortURL})
+			continue
+		}
+		item.Key = keys[i]
+		pending = append(pending, item)
+		pendingKeys = append(pendingKeys, keys[i])
+	}
+
+	var writeMu sync.Mutex
+	var writeErr error
+	write := func(entry checkpointEntry) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		if writeErr != nil {
+			return writeErr
+		}
+		line, _ := json.Marshal(entry)
+		if _, err := file.Write(append(line, '\n')); err != nil {
+			writeErr = err
+			return err
+		}
+		writeErr = file.Sync()
+		return writeErr
+	}
+
+	results, batchErr := c.RunBatch(ctx, pending, func(c *Client, input interface{}) (interface{}, error) {
+		item := input.(BulkJobItem)
+		var link *ShortLink
+		var err error
+		switch {
+		case item.Create != nil && started[item.Key]:
+			// An earlier run may have created the link without recording it.
+			link, _, err = c.GetOrCreateShortLink(*item.Create)
+		case item.Create != nil:
+			if err := write(checkpointEntry{Key: item.Key, State: checkpointStarted}); err != nil {
+				return nil, fmt.Errorf("unable to write checkpoint: %v", err)
+			}
+			link, err = c.CreateShortLink(*item.Create)
+		default:
+			link, err = c.UpdateShortLink(*item.Update)
+		}
+		if err != nil {
+			return nil, err
+		}
+		write(checkpointEntry{Key: item.Key, ShortURL: link.ShortURL, CompletedAt: formatAPIDateTime(time.Now())})
+		return link, nil
+	}, BatchOptions{
+		Concurrency: options.Concurrency,
+		MaxFailures: options.MaxFailures,
+		Progress:    options.Progress,
+	})
+
+	for i, result := range results {
+		itemResult := BulkJobItemResult{Key: pendingKeys[i], Err: result.Err}
+		if link, ok := result.Output.(*ShortLink); ok && link != nil {
+			itemResult.ShortURL = link.ShortURL
+		}
+		if result.Err != nil {
+			report.Failed = append(report.Failed, itemResult)
+			continue
+		}
+		report.Succeeded = append(report.Succeeded, itemResult)
+	}
+	if writeErr != nil {
+		return report, fmt.Errorf("unable to write checkpoint: %v", writeErr)
+	}
+	return report, batchErr
+}
+
+// readCheckpoint loads completed entries and the keys of creates that were
+// started but never recorded as completed. A truncated final line, left by a
+// process that died mid-write, is ignored.
+func readCheckpoint(path string) (map[string]checkpointEntry, map[string]bool, error) {
+	completed := map[string]checkpointEntry{}
+	started := map[string]bool{}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return completed, started, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry checkpointEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Key == "" {
+			continue
+		}
+		if entry.State == checkpointStarted {
+			started[entry.Key] = true
+			continue
+		}
+		completed[entry.Key] = entry
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return completed, started, nil
+}
+
+// terminateLastLine appends a newline if the file ends in a truncated entry,
+// so new entries start on their own line.
+func terminateLastLine(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil || info.Size() == 0 {
+		return err
+	}
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, info.Size()-1); err != nil {
+		return err
+	}
+	if last[0] == '\n' {
+		return nil
+	}
+	_, err = file.Write([]byte{'\n'})
+	return err
+}
@@ -0,0 +1,202 @@
+package tly
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// =====================
+// Bulk Tagging
+// =====================
+
+// TagChangeOptions selects links and the tags added to or removed from them.
+type TagChangeOptions struct {
+	// Filter is passed to the list endpoint.
+	Filter ListShortLinksOptions
+	// Predicates must all match for a link to be changed.
+	Predicates []LinkPredicate
+	// TagIDs and TagNames name the tags to add or remove. Names are matched
+	// without regard to case.
+	TagIDs   []int
+	TagNames []string
+	// CreateMissingTags creates named tags that do not exist yet when adding.
+	CreateMissingTags bool
+	// BatchSize is the number of links per BulkUpdateLinks call.
+	// Defaults to DefaultBulkChunkSize.
+	BatchSize int
+	// DryRun computes the changes without applying them.
+	DryRun bool
+}
+
+// TagChangeResult reports the tags of one changed link before and after.
+type TagChangeResult struct {
+	Link   ShortLink
+	Before []int
+	After  []int
+	Err    error
+}
+
+// AddTagsToLinks adds tags to every matching link, keeping its existing tags.
+func (c *Client) AddTagsToLinks(ctx context.Context, options TagChangeOptions) ([]TagChangeResult, error) {
+	return c.changeLinkTags(ctx, options, true)
+}
+
+// RemoveTagsFromLinks removes tags from every matching link, keeping its other tags.
+func (c *Client) RemoveTagsFromLinks(ctx context.Context, options TagChangeOptions) ([]TagChangeResult, error) {
+	return c.changeLinkTags(ctx, options, false)
+}
+
+// changeLinkTags computes the new tag set for each matching link. Links that
+// end up with the same non-empty set are updated together through
+// BulkUpdateLinks; links left without tags are updated one at a time, since
+// an empty tag list cannot be expressed in a bulk update.
+func (c *Client) changeLinkTags(ctx context.Context, options TagChangeOptions, add bool) ([]TagChangeResult, error) {
+	bound := c.WithContext(ctx)
+	named, err := bound.resolveTagNames(options.TagNames, add && options.CreateMissingTags, !add)
+	if err != nil {
+		return nil, err
+	}
+	change := append(append([]int(nil), options.TagIDs...), named...)
+	if len(change) == 0 {
+		return nil, fmt.Errorf("no tags to change")
+	}
+
+	links, err := bound.ListAllShortLinks(options.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []TagChangeResult
+	for _, link := range links {
+		matched, err := matchLink(bound, link, options.Predicates)
+		if err != nil {
+			results = append(results, TagChangeResult{Link: link, Err: err})
+			continue
+		}
+		if !matched {
+			continue
+		}
+		before := uniqueSortedIDs(link.TagIDs())
+		var after []int
+		if add {
+			after = uniqueSortedIDs(append(append([]int(nil), before...), change...))
+		} else {
+			after = withoutIDs(before, change)
+		}
+		if equalIDs(before, after) {
+			continue
+		}
+		results = append(results, TagChangeResult{Link: link, Before: before, After: after})
+	}
+	if options.DryRun {
+		return results, nil
+	}
+
+	batchSize := options.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBulkChunkSize
+	}
+	groups := map[string][]int{}
+	var keys []string
+	for i, result := range results {
+		if result.Err != nil {
+			continue
+		}
+		if len(result.After) == 0 {
+			results[i].Err = bound.setLinkTags(result.Link, []int{})
+			continue
+		}
+		key := idsKey(result.After)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+
+	for _, key := range keys {
+		indexes := groups[key]
+		for start := 0; start < len(indexes); start += batchSize {
+			end := start + batchSize
+			if end > len(indexes) {
+				end = len(indexes)
+			}
+			chunk := indexes[start:end]
+			entries := make([]BulkUpdateLink, len(chunk))
+			for j, i := range chunk {
+				entries[j] = BulkUpdateLink{
+					ShortURL: results[i].Link.ShortURL,
+					LongURL:  results[i].Link.LongURL,
+				}
+			}
+			_, err := bound.BulkUpdateLinks(BulkUpdateRequest{
+				Links: entries,
+				Tags:  results[chunk[0]].After,
+			})
+			for _, i := range chunk {
+				results[i].Err = err
+			}
+		}
+	}
+	return results, ctx.Err()
+}
+
+// setLinkTags replaces a link's tags. It sends the body directly because the
+// typed update request omits an empty tag list.
+func (c *Client) setLinkTags(link ShortLink, tags []int) error {
+	reqBody := map[string]interface{}{
+		"short_url": link.ShortURL,
+		"long_url":  link.LongURL,
+		"tags":      tags,
+	}
+	return c.doRequest(http.MethodPut, "/api/v1/link", nil, reqBody, nil)
+}
+
+func uniqueSortedIDs(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	var result []int
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	sort.Ints(result)
+	return result
+}
+
+func withoutIDs(ids, remove []int) []int {
+	drop := make(map[int]bool, len(remove))
+	for _, id := range remove {
+		drop[id] = true
+	}
+	var result []int
+	for _, id := range ids {
+		if !drop[id] {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+func equalIDs(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func idsKey(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprint(id)
+	}
+	return strings.Join(parts, ",")
+}
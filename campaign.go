@@ -0,0 +1,225 @@
+package tly
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// =====================
+// Campaign Generator
+// =====================
+
+// CampaignDimension is one axis of a campaign matrix, such as channels or
+// creatives.
+type CampaignDimension struct {
+	// Name is the key the value is exposed under in templates, e.g. "channel".
+	Name string
+	// UTMParam, when set, receives the value on each link, e.g. utm_source.
+	UTMParam string
+	Values   []string
+}
+
+// Campaign describes a matrix of links: one per combination of dimension values.
+type Campaign struct {
+	// BaseURL is the destination every link starts from.
+	BaseURL string
+	// Preset supplies UTM values shared by every link. Dimension values
+	// override the preset for their UTMParam.
+	Preset UTMPreset
+	// Dimensions are expanded in order into every combination.
+	Dimensions []CampaignDimension
+	// ShortIDTemplate and DescriptionTemplate are text/template strings
+	// executed with a map from dimension name to value, plus "campaign"
+	// holding Preset.Campaign. The "slug" function lower-cases a value and
+	// replaces unsafe characters. CreateCampaign requires a ShortIDTemplate;
+	// Expand alone leaves ShortID empty without one.
+	ShortIDTemplate     string
+	DescriptionTemplate string
+	// Domain, Tags and Pixels are shared by every link.
+	Domain string
+	Tags   []int
+	Pixels []int
+}
+
+// CampaignLink is one cell of an expanded campaign matrix.
+type CampaignLink struct {
+	Values      map[string]string
+	LongURL     string
+	ShortID     string
+	Description string
+	ShortURL    string
+	Err         error
+}
+
+// CampaignResult holds the links of a campaign in expansion order.
+type CampaignResult struct {
+	Dimensions []CampaignDimension
+	Links      []CampaignLink
+}
+
+// Get returns the link for the given dimension values, given in dimension order.
+func (r *CampaignResult) Get(values ...string) (*CampaignLink, bool) {
+	if len(values) != len(r.Dimensions) {
+		return nil, false
+	}
+	for i := range r.Links {
+		link := &r.Links[i]
+		match := true
+		for j, dimension := range r.Dimensions {
+			if link.Values[dimension.Name] != values[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return link, true
+		}
+	}
+	return nil, false
+}
+
+var campaignFuncs = template.FuncMap{
+	"slug":  NormalizeUTMValue,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// Expand builds every combination of dimension values with its long URL,
+// short ID and description, without calling the API.
+func (c Campaign) Expand() ([]CampaignLink, error) {
+	if err := validateHTTPURL(c.BaseURL); err != nil {
+		return nil, err
+	}
+	if len(c.Dimensions) == 0 {
+		return nil, fmt.Errorf("campaign has no dimensions")
+	}
+	seenNames := map[string]bool{}
+	for _, dimension := range c.Dimensions {
+		if dimension.Name == "" || len(dimension.Values) == 0 {
+			return nil, fmt.Errorf("campaign dimension %q needs a name and at least one value", dimension.Name)
+		}
+		if seenNames[dimension.Name] {
+			return nil, fmt.Errorf("duplicate campaign dimension %q", dimension.Name)
+		}
+		seenNames[dimension.Name] = true
+	}
+	shortIDTemplate, err := template.New("short_id").Funcs(campaignFuncs).Option("missingkey=error").Parse(c.ShortIDTemplate)
+	if err != nil {
+		return nil, err
+	}
+	descriptionTemplate, err := template.New("description").Funcs(campaignFuncs).Option("missingkey=error").Parse(c.DescriptionTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	var links []CampaignLink
+	shortIDs := map[string]bool{}
+	combination := make([]int, len(c.Dimensions))
+	for {
+		values := map[string]string{}
+		data := map[string]string{"campaign": c.Preset.Campaign}
+		params := c.Preset.Params()
+		for i, dimension := range c.Dimensions {
+			value := dimension.Values[combination[i]]
+			values[dimension.Name] = value
+			data[dimension.Name] = value
+			if dimension.UTMParam != "" {
+				params[dimension.UTMParam] = value
+			}
+		}
+
+		link := CampaignLink{Values: values}
+		if link.LongURL, err = applyUTMParams(c.BaseURL, params, true); err != nil {
+			return nil, err
+		}
+		if link.ShortID, err = executeCampaignTemplate(shortIDTemplate, data); err != nil {
+			return nil, err
+		}
+		if link.Description, err = executeCampaignTemplate(descriptionTemplate, data); err != nil {
+			return nil, err
+		}
+		if link.ShortID != "" {
+			verr := &ValidationError{}
+			validateShortID(verr, link.ShortID)
+			if err := verr.err(); err != nil {
+				return nil, err
+			}
+			if shortIDs[link.ShortID] {
+				return nil, fmt.Errorf("short id template produced duplicate %q", link.ShortID)
+			}
+			shortIDs[link.ShortID] = true
+		}
+		links = append(links, link)
+
+		// Advance the combination like an odometer, last dimension fastest.
+		i := len(combination) - 1
+		for ; i >= 0; i-- {
+			combination[i]++
+			if combination[i] < len(c.Dimensions[i].Values) {
+				break
+			}
+			combination[i] = 0
+		}
+		if i < 0 {
+			return links, nil
+		}
+	}
+}
+
+func executeCampaignTemplate(tmpl *template.Template, data map[string]string) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// CreateCampaign expands the campaign and creates its links through
+// BulkShortenLinksChunked with the campaign's shared tags and pixels. Short
+// URLs are taken from the API response when it returns per-link data, and
+// built from the domain and short ID otherwise, so every link needs a short
+// ID from ShortIDTemplate.
+func (c *Client) CreateCampaign(campaign Campaign, options BulkShortenChunkOptions) (*CampaignResult, error) {
+	links, err := campaign.Expand()
+	if err != nil {
+		return nil, err
+	}
+	for _, link := range links {
+		if link.ShortID == "" {
+			return nil, fmt.Errorf("campaign short id template must give every link a short id")
+		}
+	}
+
+	entries := make([]BulkShortenLink, len(links))
+	for i, link := range links {
+		shortID := link.ShortID
+		entry := BulkShortenLink{LongURL: link.LongURL, Backhalf: &shortID}
+		if link.Description != "" {
+			description := link.Description
+			entry.Description = &description
+		}
+		entries[i] = entry
+	}
+	results, err := c.BulkShortenLinksChunked(BulkShortenRequest{
+		Domain: campaign.Domain,
+		Links:  entries,
+		Tags:   campaign.Tags,
+		Pixels: campaign.Pixels,
+	}, options)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, result := range results {
+		links[i].Err = result.Err
+		switch {
+		case result.ShortLink != nil:
+			links[i].ShortURL = result.ShortLink.ShortURL
+		case result.Err == nil:
+			links[i].ShortURL = shortURLFor(campaign.Domain, links[i].ShortID)
+		}
+	}
+	return &CampaignResult{Dimensions: campaign.Dimensions, Links: links}, nil
+}
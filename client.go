@@ -2,6 +2,7 @@ package tly
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -9,6 +10,8 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // Client is the main API client for T.LY.
@@ -16,6 +19,15 @@ type Client struct {
 	APIKey  string
 	BaseURL string
 	Client  *http.Client
+
+	// SkipValidation disables client-side request validation before sending.
+	SkipValidation bool
+
+	// RateLimiter, when set, paces every request sent by the client.
+	RateLimiter *RateLimiter
+
+	ctx   context.Context
+	links *linkIndex
 }
 
 // APIError is returned when the T.LY API responds with a non-2xx status.
@@ -28,16 +40,80 @@ func (e *APIError) Error() string {
 	return fmt.Sprintf("API error (status %d): %s", e.StatusCode, e.Body)
 }
 
+// RateLimiter spaces out requests so that at most a fixed number are sent
+// per interval. It is safe for concurrent use.
+type RateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// NewRateLimiter allows up to requests calls to Wait per period.
+func NewRateLimiter(requests int, period time.Duration) *RateLimiter {
+	if requests <= 0 {
+		requests = 1
+	}
+	return &RateLimiter{interval: period / time.Duration(requests)}
+}
+
+// Wait blocks until the next request may be sent or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // NewClient creates a new T.LY API client.
 func NewClient(apiKey string) *Client {
 	return &Client{
 		APIKey:  apiKey,
 		BaseURL: "https://api.t.ly",
 		Client:  &http.Client{},
+		links:   newLinkIndex(),
 	}
 }
 
+// WithContext returns a shallow copy of the client whose requests are bound
+// to ctx, so cancelling ctx aborts rate limiter waits and in-flight requests.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	c.linkIndex() // make sure the copy shares the original's index
+	clone := *c
+	clone.ctx = ctx
+	return &clone
+}
+
+func (c *Client) requestContext() context.Context {
+	if c.ctx != nil {
+		return c.ctx
+	}
+	return context.Background()
+}
+
 func (c *Client) doRequestRaw(method, path string, query url.Values, body interface{}) ([]byte, error) {
+	ctx := c.requestContext()
+	if c.RateLimiter != nil {
+		if err := c.RateLimiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
 	requestURL := strings.TrimRight(c.BaseURL, "/") + path
 	if query != nil && len(query) > 0 {
 		requestURL += "?" + query.Encode()
@@ -54,7 +130,7 @@ func (c *Client) doRequestRaw(method, path string, query url.Values, body interf
 		reqBody = bytes.NewBuffer(nil)
 	}
 
-	req, err := http.NewRequest(method, requestURL, reqBody)
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, reqBody)
 	if err != nil {
 		return nil, err
 	}
@@ -113,6 +189,25 @@ func addIndexedIntSlice(query url.Values, key string, values []int) {
 	}
 }
 
+// apiDateTimeLayout is the datetime format used by the T.LY API, in UTC.
+const apiDateTimeLayout = "2006-01-02 15:04:05"
+
+func formatAPIDateTime(t time.Time) string {
+	return t.UTC().Format(apiDateTimeLayout)
+}
+
+// parseAPIDateTime parses the datetime formats returned and accepted by the API.
+// Values without a zone are interpreted as UTC.
+func parseAPIDateTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{time.RFC3339Nano, apiDateTimeLayout, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid datetime %q", value)
+}
+
 // =====================
 // Pixel Management
 // =====================
@@ -144,6 +239,11 @@ type PixelUpdateRequest struct {
 
 // CreatePixel calls the API to create a new pixel.
 func (c *Client) CreatePixel(reqData PixelCreateRequest) (*Pixel, error) {
+	if !c.SkipValidation {
+		if err := reqData.Validate(); err != nil {
+			return nil, err
+		}
+	}
 	var pixel Pixel
 	err := c.doRequest(http.MethodPost, "/api/v1/link/pixel", nil, reqData, &pixel)
 	if err != nil {
@@ -175,6 +275,11 @@ func (c *Client) GetPixel(id int) (*Pixel, error) {
 
 // UpdatePixel updates an existing pixel.
 func (c *Client) UpdatePixel(reqData PixelUpdateRequest) (*Pixel, error) {
+	if !c.SkipValidation {
+		if err := reqData.Validate(); err != nil {
+			return nil, err
+		}
+	}
 	path := fmt.Sprintf("/api/v1/link/pixel/%d", reqData.ID)
 	var pixel Pixel
 	err := c.doRequest(http.MethodPut, path, nil, reqData, &pixel)
@@ -213,6 +318,39 @@ type ShortLink struct {
 	Pixels           []interface{} `json:"pixels,omitempty"`
 }
 
+// TagIDs returns the IDs of the tags attached to the link.
+func (l *ShortLink) TagIDs() []int {
+	return objectIDs(l.Tags)
+}
+
+// PixelIDs returns the IDs of the pixels attached to the link.
+func (l *ShortLink) PixelIDs() []int {
+	return objectIDs(l.Pixels)
+}
+
+// objectIDs extracts IDs from a list of related objects as decoded from JSON,
+// accepting either bare IDs or objects with an "id" field.
+func objectIDs(objects []interface{}) []int {
+	var ids []int
+	for _, object := range objects {
+		value := object
+		if m, ok := object.(map[string]interface{}); ok {
+			value = m["id"]
+		}
+		switch v := value.(type) {
+		case float64:
+			ids = append(ids, int(v))
+		case int:
+			ids = append(ids, v)
+		case string:
+			if id, err := strconv.Atoi(v); err == nil {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
 // ShortLinkCreateRequest is used to create a short link.
 type ShortLinkCreateRequest struct {
 	LongURL          string      `json:"long_url"`
@@ -245,6 +383,11 @@ type ShortLinkUpdateRequest struct {
 
 // CreateShortLink creates a new short link.
 func (c *Client) CreateShortLink(reqData ShortLinkCreateRequest) (*ShortLink, error) {
+	if !c.SkipValidation {
+		if err := reqData.Validate(); err != nil {
+			return nil, err
+		}
+	}
 	var link ShortLink
 	err := c.doRequest(http.MethodPost, "/api/v1/link/shorten", nil, reqData, &link)
 	if err != nil {
@@ -267,11 +410,17 @@ func (c *Client) GetShortLink(shortURL string) (*ShortLink, error) {
 
 // UpdateShortLink updates an existing short link.
 func (c *Client) UpdateShortLink(reqData ShortLinkUpdateRequest) (*ShortLink, error) {
+	if !c.SkipValidation {
+		if err := reqData.Validate(); err != nil {
+			return nil, err
+		}
+	}
 	var link ShortLink
 	err := c.doRequest(http.MethodPut, "/api/v1/link", nil, reqData, &link)
 	if err != nil {
 		return nil, err
 	}
+	c.linkIndex().forget(reqData.ShortURL)
 	return &link, nil
 }
 
@@ -280,7 +429,11 @@ func (c *Client) DeleteShortLink(shortURL string) error {
 	reqBody := map[string]string{
 		"short_url": shortURL,
 	}
-	return c.doRequest(http.MethodDelete, "/api/v1/link", nil, reqBody, nil)
+	err := c.doRequest(http.MethodDelete, "/api/v1/link", nil, reqBody, nil)
+	if err == nil {
+		c.linkIndex().forget(shortURL)
+	}
+	return err
 }
 
 // ExpandRequest is used to expand a short link.
@@ -327,6 +480,15 @@ type ShortLinkListResponse struct {
 
 // ListShortLinksDetailed retrieves short links with typed filter options.
 func (c *Client) ListShortLinksDetailed(options ListShortLinksOptions) (*ShortLinkListResponse, error) {
+	var result ShortLinkListResponse
+	err := c.doRequest(http.MethodGet, "/api/v1/link/list", options.query(), nil, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (options ListShortLinksOptions) query() url.Values {
 	query := url.Values{}
 	if options.Search != "" {
 		query.Set("search", options.Search)
@@ -343,13 +505,46 @@ func (c *Client) ListShortLinksDetailed(options ListShortLinksOptions) (*ShortLi
 	if options.Page > 0 {
 		query.Set("page", strconv.Itoa(options.Page))
 	}
+	return query
+}
 
-	var result ShortLinkListResponse
-	err := c.doRequest(http.MethodGet, "/api/v1/link/list", query, nil, &result)
+// EachShortLink walks every page of ListShortLinksDetailed, starting at
+// options.Page (or the first page), and calls fn for each link. Iteration
+// stops at the first error returned by the API or by fn.
+func (c *Client) EachShortLink(options ListShortLinksOptions, fn func(link ShortLink) error) error {
+	page := options.Page
+	if page <= 0 {
+		page = 1
+	}
+	for {
+		options.Page = page
+		result, err := c.ListShortLinksDetailed(options)
+		if err != nil {
+			return err
+		}
+		for _, link := range result.Data {
+			if err := fn(link); err != nil {
+				return err
+			}
+		}
+		if len(result.Data) == 0 || result.CurrentPage >= result.LastPage {
+			return nil
+		}
+		page = result.CurrentPage + 1
+	}
+}
+
+// ListAllShortLinks collects every link matching options across all pages.
+func (c *Client) ListAllShortLinks(options ListShortLinksOptions) ([]ShortLink, error) {
+	var links []ShortLink
+	err := c.EachShortLink(options, func(link ShortLink) error {
+		links = append(links, link)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	return &result, nil
+	return links, nil
 }
 
 // ListShortLinks retrieves a list of short links using optional query parameters.
@@ -365,10 +560,12 @@ func (c *Client) ListShortLinks(queryParams map[string]string) (string, error) {
 
 // BulkShortenLink represents one entry in a bulk shorten request.
 type BulkShortenLink struct {
-	LongURL     string  `json:"long_url"`
-	Backhalf    *string `json:"backhalf,omitempty"`
-	Password    *string `json:"password,omitempty"`
-	Description *string `json:"description,omitempty"`
+	LongURL          string  `json:"long_url"`
+	Backhalf         *string `json:"backhalf,omitempty"`
+	Password         *string `json:"password,omitempty"`
+	Description      *string `json:"description,omitempty"`
+	ExpireAtDatetime *string `json:"expire_at_datetime,omitempty"`
+	ExpireAtViews    *int    `json:"expire_at_views,omitempty"`
 }
 
 // BulkShortenRequest is used for bulk shortening of links.
@@ -392,11 +589,13 @@ func (c *Client) BulkShortenLinks(reqData BulkShortenRequest) (string, error) {
 
 // BulkUpdateLink represents one entry in a bulk update request.
 type BulkUpdateLink struct {
-	ShortURL    string  `json:"short_url"`
-	LongURL     string  `json:"long_url,omitempty"`
-	Backhalf    *string `json:"backhalf,omitempty"`
-	Password    *string `json:"password,omitempty"`
-	Description *string `json:"description,omitempty"`
+	ShortURL         string  `json:"short_url"`
+	LongURL          string  `json:"long_url,omitempty"`
+	Backhalf         *string `json:"backhalf,omitempty"`
+	Password         *string `json:"password,omitempty"`
+	Description      *string `json:"description,omitempty"`
+	ExpireAtDatetime *string `json:"expire_at_datetime,omitempty"`
+	ExpireAtViews    *int    `json:"expire_at_views,omitempty"`
 }
 
 // BulkUpdateRequest is used for bulk updating links.
@@ -411,12 +610,48 @@ type BulkUpdateRequest struct {
 func (c *Client) BulkUpdateLinks(reqData BulkUpdateRequest) (string, error) {
 	var raw []byte
 	err := c.doRequest(http.MethodPost, "/api/v1/link/bulk/update", nil, reqData, &raw)
+	// Even a failed request may have updated some links.
+	if shortURLs, ok := bulkUpdateShortURLs(reqData.Links); ok {
+		c.linkIndex().forget(shortURLs...)
+	} else {
+		c.linkIndex().clear()
+	}
 	if err != nil {
 		return "", err
 	}
 	return string(raw), nil
 }
 
+// bulkUpdateShortURLs lists the short URLs named in a bulk update. It reports
+// false when links is in a form it cannot read.
+func bulkUpdateShortURLs(links interface{}) ([]string, bool) {
+	if typed, ok := links.([]BulkUpdateLink); ok {
+		shortURLs := make([]string, len(typed))
+		for i, link := range typed {
+			shortURLs[i] = link.ShortURL
+		}
+		return shortURLs, true
+	}
+	data, err := json.Marshal(links)
+	if err != nil {
+		return nil, false
+	}
+	var entries []struct {
+		ShortURL string `json:"short_url"`
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, false
+	}
+	shortURLs := make([]string, len(entries))
+	for i, entry := range entries {
+		if entry.ShortURL == "" {
+			return nil, false
+		}
+		shortURLs[i] = entry.ShortURL
+	}
+	return shortURLs, true
+}
+
 // =====================
 // Stats Management
 // =====================
@@ -685,27 +920,32 @@ func (c *Client) GetQRCode(reqData QRCodeRequest) ([]byte, error) {
 
 // QRCodeUpdateRequest includes QR code customization options.
 type QRCodeUpdateRequest struct {
-	ShortURL        string  `json:"short_url"`
-	Image           *string `json:"image,omitempty"`
-	BackgroundColor *string `json:"background_color,omitempty"`
-	CornerDotsColor *string `json:"corner_dots_color,omitempty"`
-	DotsColor       *string `json:"dots_color,omitempty"`
-	DotsStyle       *string `json:"dots_style,omitempty"`
-	CornerStyle     *string `json:"corner_style,omitempty"`
+	ShortURL        string         `json:"short_url"`
+	Image           *string        `json:"image,omitempty"`
+	BackgroundColor *string        `json:"background_color,omitempty"`
+	CornerDotsColor *string        `json:"corner_dots_color,omitempty"`
+	DotsColor       *string        `json:"dots_color,omitempty"`
+	DotsStyle       *QRDotsStyle   `json:"dots_style,omitempty"`
+	CornerStyle     *QRCornerStyle `json:"corner_style,omitempty"`
 }
 
 // QRCode represents a QR code record.
 type QRCode struct {
-	ID            int                    `json:"id"`
-	ShortURL      string                 `json:"short_url"`
-	QRCodeOptions map[string]interface{} `json:"qr_code_options"`
-	TeamID        int                    `json:"team_id"`
-	UserID        int                    `json:"user_id"`
-	UpdatedAt     string                 `json:"updated_at"`
+	ID            int           `json:"id"`
+	ShortURL      string        `json:"short_url"`
+	QRCodeOptions QRCodeOptions `json:"qr_code_options"`
+	TeamID        int           `json:"team_id"`
+	UserID        int           `json:"user_id"`
+	UpdatedAt     string        `json:"updated_at"`
 }
 
 // UpdateQRCode updates QR code options for a short link.
 func (c *Client) UpdateQRCode(reqData QRCodeUpdateRequest) (*QRCode, error) {
+	if !c.SkipValidation {
+		if err := reqData.Validate(); err != nil {
+			return nil, err
+		}
+	}
 	var qrCode QRCode
 	err := c.doRequest(http.MethodPut, "/api/v1/link/qr-code", nil, reqData, &qrCode)
 	if err != nil {
@@ -774,6 +1014,65 @@ func (c *Client) UpdateTag(id int, tagValue string) (*Tag, error) {
 	return &tag, nil
 }
 
+// ResolveTagNames returns the IDs of the named tags, matching names without
+// regard to case. Missing tags are created when createMissing is true and
+// reported as an error otherwise.
+func (c *Client) ResolveTagNames(names []string, createMissing bool) ([]int, error) {
+	return c.resolveTagNames(names, createMissing, false)
+}
+
+func (c *Client) resolveTagNames(names []string, createMissing, skipMissing bool) ([]int, error) {
+	byName, err := c.resolveTagIDs(names, createMissing)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int
+	for _, name := range names {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if key == "" {
+			continue
+		}
+		if id, ok := byName[key]; ok {
+			ids = append(ids, id)
+		} else if !skipMissing {
+			return nil, fmt.Errorf("tag %q not found", name)
+		}
+	}
+	return ids, nil
+}
+
+// resolveTagIDs maps the lower-cased, trimmed form of each name to its tag
+// ID. Missing tags are created when createMissing is true and left out of the
+// map otherwise.
+func (c *Client) resolveTagIDs(names []string, createMissing bool) (map[string]int, error) {
+	if len(names) == 0 {
+		return map[string]int{}, nil
+	}
+	tags, err := c.ListTags()
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]int, len(tags))
+	for _, tag := range tags {
+		byName[strings.ToLower(strings.TrimSpace(tag.Tag))] = tag.ID
+	}
+	if !createMissing {
+		return byName, nil
+	}
+	for _, name := range names {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if _, ok := byName[key]; ok || key == "" {
+			continue
+		}
+		tag, err := c.CreateTag(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		byName[key] = tag.ID
+	}
+	return byName, nil
+}
+
 // DeleteTag deletes a tag by its ID.
 func (c *Client) DeleteTag(id int) error {
 	path := fmt.Sprintf("/api/v1/link/tag/%d", id)
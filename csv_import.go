@@ -0,0 +1,438 @@
+package tly
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// =====================
+// CSV Import
+// =====================
+
+// Field names understood by ImportLinksCSV.
+const (
+	CSVFieldLongURL     = "long_url"
+	CSVFieldShortID     = "short_id"
+	CSVFieldDomain      = "domain"
+	CSVFieldDescription = "description"
+	CSVFieldTags        = "tags"
+	CSVFieldExpireAt    = "expire_at_datetime"
+	CSVFieldExpireViews = "expire_at_views"
+	CSVFieldPassword    = "password"
+)
+
+var csvImportFields = []string{
+	CSVFieldLongURL,
+	CSVFieldShortID,
+	CSVFieldDomain,
+	CSVFieldDescription,
+	CSVFieldTags,
+	CSVFieldExpireAt,
+	CSVFieldExpireViews,
+	CSVFieldPassword,
+}
+
+// CSVImportOptions controls how ImportLinksCSV reads and creates links.
+type CSVImportOptions struct {
+	// Columns maps a field name (see the CSVField constants) to the header of
+	// the column holding it. Fields without a mapping are read from a column
+	// named after the field, if there is one. Headers match without regard to case.
+	Columns map[string]string
+	// Domain is used for rows without a domain value.
+	Domain string
+	// TagSeparator splits tag names within a cell. Defaults to ";".
+	TagSeparator string
+	// CreateMissingTags creates tags named in the file that do not exist yet.
+	CreateMissingTags bool
+	// UseBulk creates rows that have a short_id through
+	// BulkShortenLinksChunked instead of one CreateShortLink call per row.
+	// Rows without a short_id are still created one at a time so their short
+	// URL is known.
+	UseBulk bool
+	// Concurrency is the number of parallel create calls.
+	Concurrency int
+	// ValidateOnly checks every row without creating anything.
+	ValidateOnly bool
+}
+
+// CSVRowError is a problem with one CSV row. Row numbers count the header as
+// row 1, matching spreadsheet row numbers.
+type CSVRowError struct {
+	Row int
+	Err error
+}
+
+func (e CSVRowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+
+// CSVImportError is returned when rows fail validation. No links or tags are created.
+type CSVImportError struct {
+	Rows []CSVRowError
+}
+
+func (e *CSVImportError) Error() string {
+	parts := make([]string, len(e.Rows))
+	for i, row := range e.Rows {
+		parts[i] = row.Error()
+	}
+	return fmt.Sprintf("%d invalid rows: %s", len(e.Rows), strings.Join(parts, "; "))
+}
+
+// CSVImportReport summarises an import.
+type CSVImportReport struct {
+	Rows    int
+	Created int
+	Failed  []CSVRowError
+}
+
+type csvImportRow struct {
+	number   int
+	record   []string
+	req      ShortLinkCreateRequest
+	tagNames []string
+	shortURL string
+	err      error
+}
+
+// ImportLinksCSV reads link rows from r, validates all of them, and creates
+// them only if every row is valid. When w is not nil the input is written back
+// to it with "short_url" and "error" columns appended.
+func (c *Client) ImportLinksCSV(ctx context.Context, r io.Reader, w io.Writer, options CSVImportOptions) (*CSVImportReport, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read csv header: %v", err)
+	}
+	columns, err := csvImportColumns(header, options.Columns)
+	if err != nil {
+		return nil, err
+	}
+	separator := options.TagSeparator
+	if separator == "" {
+		separator = ";"
+	}
+
+	var rows []*csvImportRow
+	var invalid []CSVRowError
+	for number := 2; ; number++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", number, err)
+		}
+		row, err := parseCSVImportRow(number, record, columns, separator, options.Domain)
+		if err == nil {
+			err = row.req.Validate()
+		}
+		if err != nil {
+			invalid = append(invalid, CSVRowError{Row: number, Err: err})
+		}
+		rows = append(rows, row)
+	}
+
+	bound := c.WithContext(ctx)
+	tagIDs, tagErrors, err := bound.resolveCSVTags(rows)
+	if err != nil {
+		return nil, err
+	}
+	if !options.CreateMissingTags {
+		invalid = append(invalid, tagErrors...)
+	}
+
+	report := &CSVImportReport{Rows: len(rows)}
+	if len(invalid) > 0 {
+		sort.SliceStable(invalid, func(i, j int) bool { return invalid[i].Row < invalid[j].Row })
+		report.Failed = invalid
+		return report, &CSVImportError{Rows: invalid}
+	}
+	if options.ValidateOnly {
+		return report, nil
+	}
+
+	// Missing tags are only created once every row has passed validation.
+	if options.CreateMissingTags {
+		if err := bound.createCSVTags(rows, tagIDs); err != nil {
+			return report, err
+		}
+	}
+	for _, row := range rows {
+		for _, name := range row.tagNames {
+			row.req.Tags = append(row.req.Tags, tagIDs[strings.ToLower(name)])
+		}
+		row.req.Tags = uniqueSortedIDs(row.req.Tags)
+	}
+	if options.UseBulk {
+		// Rows without a short_id go through CreateShortLink, since the bulk
+		// response may not say which short URL each of them got.
+		var bulk, single []*csvImportRow
+		for _, row := range rows {
+			if row.req.ShortID != nil {
+				bulk = append(bulk, row)
+			} else {
+				single = append(single, row)
+			}
+		}
+		if len(bulk) > 0 {
+			err = bound.createCSVRowsBulk(bulk, options.Concurrency)
+		}
+		if err == nil && len(single) > 0 {
+			err = bound.createCSVRows(ctx, single, options.Concurrency)
+		}
+	} else {
+		err = bound.createCSVRows(ctx, rows, options.Concurrency)
+	}
+
+	for _, row := range rows {
+		if row.err != nil {
+			report.Failed = append(report.Failed, CSVRowError{Row: row.number, Err: row.err})
+		} else {
+			report.Created++
+		}
+	}
+	if w != nil {
+		if writeErr := writeCSVImportResult(w, header, rows); writeErr != nil && err == nil {
+			err = writeErr
+		}
+	}
+	return report, err
+}
+
+func csvImportColumns(header []string, mapping map[string]string) (map[string]int, error) {
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		positions[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	columns := map[string]int{}
+	for _, field := range csvImportFields {
+		name, mapped := mapping[field]
+		if !mapped {
+			name = field
+		}
+		if i, ok := positions[strings.ToLower(strings.TrimSpace(name))]; ok {
+			columns[field] = i
+		} else if mapped {
+			return nil, fmt.Errorf("column %q for %s not found in csv header", name, field)
+		}
+	}
+	for field := range mapping {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("unknown csv import field %q", field)
+		}
+	}
+	if _, ok := columns[CSVFieldLongURL]; !ok {
+		return nil, fmt.Errorf("csv has no %s column", CSVFieldLongURL)
+	}
+	return columns, nil
+}
+
+func parseCSVImportRow(number int, record []string, columns map[string]int, separator, domain string) (*csvImportRow, error) {
+	row := &csvImportRow{number: number, record: record}
+	value := func(field string) (string, bool) {
+		i, ok := columns[field]
+		if !ok || i >= len(record) {
+			return "", false
+		}
+		v := strings.TrimSpace(record[i])
+		return v, v != ""
+	}
+
+	row.req.LongURL, _ = value(CSVFieldLongURL)
+	row.req.Domain = domain
+	if v, ok := value(CSVFieldDomain); ok {
+		row.req.Domain = v
+	}
+	if v, ok := value(CSVFieldShortID); ok {
+		row.req.ShortID = &v
+	}
+	if v, ok := value(CSVFieldDescription); ok {
+		row.req.Description = &v
+	}
+	if v, ok := value(CSVFieldPassword); ok {
+		row.req.Password = &v
+	}
+	if v, ok := value(CSVFieldTags); ok {
+		for _, name := range strings.Split(v, separator) {
+			if name = strings.TrimSpace(name); name != "" {
+				row.tagNames = append(row.tagNames, name)
+			}
+		}
+	}
+	if v, ok := value(CSVFieldExpireAt); ok {
+		t, err := parseAPIDateTime(v)
+		if err != nil {
+			return row, err
+		}
+		formatted := formatAPIDateTime(t)
+		row.req.ExpireAtDatetime = &formatted
+	}
+	if v, ok := value(CSVFieldExpireViews); ok {
+		views, err := strconv.Atoi(v)
+		if err != nil {
+			return row, fmt.Errorf("invalid expire_at_views %q", v)
+		}
+		row.req.ExpireAtViews = &views
+	}
+	return row, nil
+}
+
+// resolveCSVTags looks up the IDs of the tags named in rows and reports
+// every name that does not exist yet. It never creates tags.
+func (c *Client) resolveCSVTags(rows []*csvImportRow) (map[string]int, []CSVRowError, error) {
+	var names []string
+	for _, row := range rows {
+		names = append(names, row.tagNames...)
+	}
+	if len(names) == 0 {
+		return map[string]int{}, nil, nil
+	}
+
+	tags, err := c.ListTags()
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := make(map[string]int, len(tags))
+	for _, tag := range tags {
+		ids[strings.ToLower(strings.TrimSpace(tag.Tag))] = tag.ID
+	}
+
+	var rowErrors []CSVRowError
+	for _, row := range rows {
+		for _, name := range row.tagNames {
+			key := strings.ToLower(name)
+			if _, ok := ids[key]; !ok {
+				rowErrors = append(rowErrors, CSVRowError{Row: row.number, Err: fmt.Errorf("tag %q not found", name)})
+			}
+		}
+	}
+	return ids, rowErrors, nil
+}
+
+// createCSVTags creates the tags named in rows that are missing from ids and
+// adds their IDs to it.
+func (c *Client) createCSVTags(rows []*csvImportRow, ids map[string]int) error {
+	for _, row := range rows {
+		for _, name := range row.tagNames {
+			key := strings.ToLower(name)
+			if _, ok := ids[key]; ok {
+				continue
+			}
+			tag, err := c.CreateTag(name)
+			if err != nil {
+				return err
+			}
+			ids[key] = tag.ID
+		}
+	}
+	return nil
+}
+
+func (c *Client) createCSVRows(ctx context.Context, rows []*csvImportRow, concurrency int) error {
+	inputs := make([]interface{}, len(rows))
+	for i, row := range rows {
+		inputs[i] = row.req
+	}
+	results, err := c.RunBatch(ctx, inputs, func(c *Client, input interface{}) (interface{}, error) {
+		return c.CreateShortLink(input.(ShortLinkCreateRequest))
+	}, BatchOptions{Concurrency: concurrency})
+
+	for i, result := range results {
+		rows[i].err = result.Err
+		if link, ok := result.Output.(*ShortLink); ok && link != nil {
+			rows[i].shortURL = link.ShortURL
+		}
+	}
+	return err
+}
+
+// createCSVRowsBulk sends rows sharing a domain and tag set in one chunked
+// bulk job each. Every row must have a short_id. Short URLs are taken from the
+// API response when it returns per-link data, and built from the domain and
+// short_id otherwise.
+func (c *Client) createCSVRowsBulk(rows []*csvImportRow, concurrency int) error {
+	groups := map[string][]*csvImportRow{}
+	var keys []string
+	for _, row := range rows {
+		key := row.req.Domain + "|" + idsKey(row.req.Tags)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], row)
+	}
+
+	for _, key := range keys {
+		group := groups[key]
+		links := make([]BulkShortenLink, len(group))
+		for i, row := range group {
+			links[i] = BulkShortenLink{
+				LongURL:          row.req.LongURL,
+				Backhalf:         row.req.ShortID,
+				Password:         row.req.Password,
+				Description:      row.req.Description,
+				ExpireAtDatetime: row.req.ExpireAtDatetime,
+				ExpireAtViews:    row.req.ExpireAtViews,
+			}
+		}
+		results, err := c.BulkShortenLinksChunked(BulkShortenRequest{
+			Domain: group[0].req.Domain,
+			Links:  links,
+			Tags:   group[0].req.Tags,
+		}, BulkShortenChunkOptions{Concurrency: concurrency})
+		if err != nil {
+			return err
+		}
+		for i, result := range results {
+			row := group[i]
+			row.err = result.Err
+			switch {
+			case result.ShortLink != nil:
+				row.shortURL = result.ShortLink.ShortURL
+			case result.Err == nil:
+				row.shortURL = shortURLFor(row.req.Domain, *row.req.ShortID)
+			}
+		}
+	}
+	return c.requestContext().Err()
+}
+
+// shortURLFor builds the short URL for a short ID on a domain given either as
+// a hostname or as a domain URL.
+func shortURLFor(domain, shortID string) string {
+	if domain == "" {
+		domain = "https://t.ly/"
+	}
+	if !strings.Contains(domain, "://") {
+		domain = "https://" + domain
+	}
+	return strings.TrimRight(domain, "/") + "/" + shortID
+}
+
+func writeCSVImportResult(w io.Writer, header []string, rows []*csvImportRow) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(append(append([]string(nil), header...), "short_url", "error")); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		errText := ""
+		if row.err != nil {
+			errText = row.err.Error()
+		}
+		record := make([]string, len(header), len(header)+2)
+		copy(record, row.record)
+		record = append(record, row.shortURL, errText)
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,80 @@
+package tly
+
+import (
+	"fmt"
+	"time"
+)
+
+// =====================
+// Link Expiration
+// =====================
+
+// Expiration holds the expiry settings for a short link. Build one with
+// ExpireAt, ExpireAfter or ExpireAfterViews and apply it with SetExpiration
+// on a create, update or bulk request. Only the fields that are set are
+// applied, so a datetime and a view limit can be combined by applying both.
+type Expiration struct {
+	Datetime *string
+	Views    *int
+}
+
+// ExpireAt expires a link at t. The time is converted to UTC before being
+// formatted for the API, so any time zone may be used. Times that are not in
+// the future are rejected.
+func ExpireAt(t time.Time) (Expiration, error) {
+	if t.IsZero() {
+		return Expiration{}, fmt.Errorf("expiration time is required")
+	}
+	if !t.After(time.Now()) {
+		return Expiration{}, fmt.Errorf("expiration time %s is in the past", t.Format(time.RFC3339))
+	}
+	value := formatAPIDateTime(t)
+	return Expiration{Datetime: &value}, nil
+}
+
+// ExpireAfter expires a link once d has elapsed from now.
+func ExpireAfter(d time.Duration) (Expiration, error) {
+	if d <= 0 {
+		return Expiration{}, fmt.Errorf("expiration duration must be positive, got %s", d)
+	}
+	return ExpireAt(time.Now().Add(d))
+}
+
+// ExpireAfterViews expires a link after n views.
+func ExpireAfterViews(n int) (Expiration, error) {
+	if n <= 0 {
+		return Expiration{}, fmt.Errorf("expiration views must be positive, got %d", n)
+	}
+	return Expiration{Views: &n}, nil
+}
+
+func (e Expiration) apply(datetime **string, views **int) {
+	if e.Datetime != nil {
+		value := *e.Datetime
+		*datetime = &value
+	}
+	if e.Views != nil {
+		value := *e.Views
+		*views = &value
+	}
+}
+
+// SetExpiration applies the set fields of e to the request.
+func (r *ShortLinkCreateRequest) SetExpiration(e Expiration) {
+	e.apply(&r.ExpireAtDatetime, &r.ExpireAtViews)
+}
+
+// SetExpiration applies the set fields of e to the request.
+func (r *ShortLinkUpdateRequest) SetExpiration(e Expiration) {
+	e.apply(&r.ExpireAtDatetime, &r.ExpireAtViews)
+}
+
+// SetExpiration applies the set fields of e to the bulk entry.
+func (l *BulkShortenLink) SetExpiration(e Expiration) {
+	e.apply(&l.ExpireAtDatetime, &l.ExpireAtViews)
+}
+
+// SetExpiration applies the set fields of e to the bulk entry.
+func (l *BulkUpdateLink) SetExpiration(e Expiration) {
+	e.apply(&l.ExpireAtDatetime, &l.ExpireAtViews)
+}
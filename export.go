@@ -0,0 +1,233 @@
+package tly
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// =====================
+// Link Export
+// =====================
+
+// Export formats supported by ExportLinks.
+const (
+	ExportFormatCSV       = "csv"
+	ExportFormatJSONLines = "jsonl"
+)
+
+// Columns available to ExportLinks.
+const (
+	ExportColumnShortURL     = "short_url"
+	ExportColumnShortID      = "short_id"
+	ExportColumnLongURL      = "long_url"
+	ExportColumnDomain       = "domain"
+	ExportColumnDescription  = "description"
+	ExportColumnCreatedAt    = "created_at"
+	ExportColumnUpdatedAt    = "updated_at"
+	ExportColumnTagIDs       = "tag_ids"
+	ExportColumnClicks       = "clicks"
+	ExportColumnUniqueClicks = "unique_clicks"
+	ExportColumnTotalQRScans = "total_qr_scans"
+)
+
+// DefaultExportColumns is used when LinkExportOptions.Columns is empty.
+var DefaultExportColumns = []string{
+	ExportColumnShortURL,
+	ExportColumnLongURL,
+	ExportColumnDescription,
+	ExportColumnCreatedAt,
+	ExportColumnClicks,
+	ExportColumnUniqueClicks,
+	ExportColumnTotalQRScans,
+}
+
+var statsColumns = map[string]bool{
+	ExportColumnClicks:       true,
+	ExportColumnUniqueClicks: true,
+	ExportColumnTotalQRScans: true,
+}
+
+// LinkExportOptions controls ExportLinks.
+type LinkExportOptions struct {
+	// Format is ExportFormatCSV (the default) or ExportFormatJSONLines.
+	Format string
+	// Columns lists the ExportColumn values to write, in order.
+	Columns []string
+	// Filter is passed to the list endpoint.
+	Filter ListShortLinksOptions
+	// StartDate and EndDate limit the stats to a date range.
+	StartDate string
+	EndDate   string
+	// Concurrency is the number of parallel stats requests.
+	Concurrency int
+}
+
+// ExportLinks writes every link matching options.Filter to w together with
+// its stats. Links are read one page at a time, stats for a page are fetched
+// concurrently, and rows are written before the next page is read, so memory
+// use does not grow with the size of the account. Stats are only fetched
+// when a stats column is selected.
+func (c *Client) ExportLinks(ctx context.Context, w io.Writer, options LinkExportOptions) error {
+	columns := options.Columns
+	if len(columns) == 0 {
+		columns = DefaultExportColumns
+	}
+	needStats := false
+	for _, column := range columns {
+		if _, ok := exportValue(column, ShortLink{}, nil); !ok {
+			return fmt.Errorf("unknown export column %q", column)
+		}
+		needStats = needStats || statsColumns[column]
+	}
+
+	var writeRow func(link ShortLink, stats *Stats) error
+	var flush func() error
+	switch options.Format {
+	case "", ExportFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(columns); err != nil {
+			return err
+		}
+		writeRow = func(link ShortLink, stats *Stats) error {
+			values := make([]string, len(columns))
+			for i, column := range columns {
+				values[i], _ = exportValue(column, link, stats)
+			}
+			return writer.Write(values)
+		}
+		flush = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+	case ExportFormatJSONLines:
+		writeRow = func(link ShortLink, stats *Stats) error {
+			line, err := exportJSONLine(columns, link, stats)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(line)
+			return err
+		}
+		flush = func() error { return nil }
+	default:
+		return fmt.Errorf("unsupported export format %q", options.Format)
+	}
+
+	var page []ShortLink
+	writePage := func() error {
+		stats := make([]*Stats, len(page))
+		if needStats {
+			inputs := make([]interface{}, len(page))
+			for i, link := range page {
+				inputs[i] = StatsRequest{ShortURL: link.ShortURL, StartDate: options.StartDate, EndDate: options.EndDate}
+			}
+			results, err := c.RunBatch(ctx, inputs, BatchGetStats, BatchOptions{Concurrency: options.Concurrency})
+			if err != nil {
+				return err
+			}
+			for i, result := range results {
+				if result.Err != nil {
+					return fmt.Errorf("stats for %s: %v", page[i].ShortURL, result.Err)
+				}
+				stats[i] = result.Output.(*Stats)
+			}
+		}
+		for i, link := range page {
+			if err := writeRow(link, stats[i]); err != nil {
+				return err
+			}
+		}
+		page = page[:0]
+		return flush()
+	}
+
+	bound := c.WithContext(ctx)
+	err := bound.EachShortLink(options.Filter, func(link ShortLink) error {
+		page = append(page, link)
+		if len(page) < exportPageSize {
+			return nil
+		}
+		return writePage()
+	})
+	if err != nil {
+		return err
+	}
+	return writePage()
+}
+
+// exportPageSize is the number of links whose stats are fetched together.
+const exportPageSize = 100
+
+func exportValue(column string, link ShortLink, stats *Stats) (string, bool) {
+	if stats == nil {
+		stats = &Stats{}
+	}
+	switch column {
+	case ExportColumnShortURL:
+		return link.ShortURL, true
+	case ExportColumnShortID:
+		return link.ShortID, true
+	case ExportColumnLongURL:
+		return link.LongURL, true
+	case ExportColumnDomain:
+		return link.Domain, true
+	case ExportColumnDescription:
+		if link.Description == nil {
+			return "", true
+		}
+		return *link.Description, true
+	case ExportColumnCreatedAt:
+		return link.CreatedAt, true
+	case ExportColumnUpdatedAt:
+		return link.UpdatedAt, true
+	case ExportColumnTagIDs:
+		return idsKey(link.TagIDs()), true
+	case ExportColumnClicks:
+		return strconv.Itoa(stats.Clicks), true
+	case ExportColumnUniqueClicks:
+		return strconv.Itoa(stats.UniqueClicks), true
+	case ExportColumnTotalQRScans:
+		return strconv.Itoa(stats.TotalQRScans), true
+	}
+	return "", false
+}
+
+// exportJSONLine encodes one JSON Lines row with keys in column order. Stats
+// are written as numbers and tag IDs as an array.
+func exportJSONLine(columns []string, link ShortLink, stats *Stats) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, column := range columns {
+		var value interface{}
+		switch column {
+		case ExportColumnTagIDs:
+			value = append([]int{}, link.TagIDs()...)
+		case ExportColumnClicks, ExportColumnUniqueClicks, ExportColumnTotalQRScans:
+			text, _ := exportValue(column, link, stats)
+			value = json.Number(text)
+		default:
+			value, _ = exportValue(column, link, stats)
+		}
+		key, err := json.Marshal(column)
+		if err != nil {
+			return nil, err
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(encoded)
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,266 @@
+package tly
+
+import (
+	"container/list"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// =====================
+// Get or Create
+// =====================
+
+// Limits of the index kept by each client for GetOrCreateShortLink.
+const (
+	linkIndexSize = 1000
+	linkIndexTTL  = 10 * time.Minute
+)
+
+// linkIndex remembers links returned by GetOrCreateShortLink, keyed by
+// account, domain and normalized long URL. Each client owns one, shared with
+// the copies made by WithContext. Entries expire after linkIndexTTL, the least
+// recently used are evicted beyond linkIndexSize, and links deleted or
+// updated through the client are dropped.
+type linkIndex struct {
+	mu    sync.Mutex
+	links map[string]*list.Element
+	order *list.List // most recently used first
+}
+
+type linkIndexEntry struct {
+	key     string
+	link    ShortLink
+	expires time.Time
+}
+
+func newLinkIndex() *linkIndex {
+	return &linkIndex{
+		links: map[string]*list.Element{},
+		order: list.New(),
+	}
+}
+
+// linkIndexInit guards the lazy creation of Client.links for clients not
+// made by NewClient.
+var linkIndexInit sync.Mutex
+
+func (c *Client) linkIndex() *linkIndex {
+	linkIndexInit.Lock()
+	defer linkIndexInit.Unlock()
+	if c.links == nil {
+		c.links = newLinkIndex()
+	}
+	return c.links
+}
+
+// destinationLocks serializes GetOrCreateShortLink calls for the same
+// account and destination across every client in the process. A lock is
+// dropped once no caller holds or waits for it.
+var destinationLocks = struct {
+	sync.Mutex
+	locks map[string]*keyLock
+}{locks: map[string]*keyLock{}}
+
+type keyLock struct {
+	sync.Mutex
+	refs int
+}
+
+func lockDestination(key string) {
+	destinationLocks.Lock()
+	lock, ok := destinationLocks.locks[key]
+	if !ok {
+		lock = &keyLock{}
+		destinationLocks.locks[key] = lock
+	}
+	lock.refs++
+	destinationLocks.Unlock()
+	lock.Lock()
+}
+
+func unlockDestination(key string) {
+	destinationLocks.Lock()
+	defer destinationLocks.Unlock()
+	lock := destinationLocks.locks[key]
+	lock.Unlock()
+	if lock.refs--; lock.refs == 0 {
+		delete(destinationLocks.locks, key)
+	}
+}
+
+func (idx *linkIndex) get(key string) (ShortLink, bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	elem, ok := idx.links[key]
+	if !ok {
+		return ShortLink{}, false
+	}
+	entry := elem.Value.(*linkIndexEntry)
+	if time.Now().After(entry.expires) {
+		idx.remove(elem)
+		return ShortLink{}, false
+	}
+	idx.order.MoveToFront(elem)
+	return entry.link, true
+}
+
+func (idx *linkIndex) put(key string, link ShortLink) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if elem, ok := idx.links[key]; ok {
+		idx.remove(elem)
+	}
+	idx.links[key] = idx.order.PushFront(&linkIndexEntry{key: key, link: link, expires: time.Now().Add(linkIndexTTL)})
+	for idx.order.Len() > linkIndexSize {
+		idx.remove(idx.order.Back())
+	}
+}
+
+// forget drops every entry for the given short URLs.
+func (idx *linkIndex) forget(shortURLs ...string) {
+	drop := make(map[string]bool, len(shortURLs))
+	for _, shortURL := range shortURLs {
+		drop[shortURL] = true
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for elem := idx.order.Front(); elem != nil; {
+		next := elem.Next()
+		if drop[elem.Value.(*linkIndexEntry).link.ShortURL] {
+			idx.remove(elem)
+		}
+		elem = next
+	}
+}
+
+// clear drops every entry.
+func (idx *linkIndex) clear() {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.links = map[string]*list.Element{}
+	idx.order.Init()
+}
+
+func (idx *linkIndex) remove(elem *list.Element) {
+	delete(idx.links, elem.Value.(*linkIndexEntry).key)
+	idx.order.Remove(elem)
+}
+
+// NormalizeLongURL returns a canonical form of a destination URL used to
+// detect duplicates: the scheme and host are lower-cased, default ports are
+// dropped, an empty path becomes "/" and query parameters are sorted.
+func NormalizeLongURL(raw string) (string, error) {
+	if err := validateHTTPURL(raw); err != nil {
+		return "", err
+	}
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", err
+	}
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	host := strings.ToLower(parsed.Hostname())
+	port := parsed.Port()
+	if port != "" && !(parsed.Scheme == "http" && port == "80") && !(parsed.Scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+	parsed.Host = host
+	if parsed.Path == "" {
+		parsed.Path = "/"
+	}
+	if parsed.RawQuery != "" {
+		parsed.RawQuery = parsed.Query().Encode()
+	}
+	return parsed.String(), nil
+}
+
+// domainHost reduces a domain given as "t.ly" or "https://t.ly/" to its host.
+// An empty domain is the API default, t.ly.
+func domainHost(domain string) string {
+	domain = strings.TrimSpace(domain)
+	if domain == "" {
+		return "t.ly"
+	}
+	if strings.Contains(domain, "://") {
+		if parsed, err := url.Parse(domain); err == nil {
+			return strings.ToLower(parsed.Hostname())
+		}
+	}
+	return strings.ToLower(strings.TrimRight(domain, "/"))
+}
+
+// GetOrCreateShortLink returns an existing link for reqData.LongURL on
+// reqData.Domain, or creates one with CreateShortLink. Long URLs are compared
+// after NormalizeLongURL. Existing links are found through links recently
+// returned by this method on the same client and, failing that, by searching
+// the list endpoint for the destination's host and path and comparing the
+// results on the client. Concurrent calls for the same destination and
+// account are serialized across all clients in the process, so only one link
+// is created. The boolean result reports whether
+// a link was created.
+func (c *Client) GetOrCreateShortLink(reqData ShortLinkCreateRequest) (*ShortLink, bool, error) {
+	normalized, err := NormalizeLongURL(reqData.LongURL)
+	if err != nil {
+		verr := &ValidationError{}
+		verr.Add("long_url", err.Error())
+		return nil, false, verr.err()
+	}
+	host := domainHost(reqData.Domain)
+	key := strings.Join([]string{c.BaseURL, c.APIKey, host, normalized}, "|")
+
+	lockDestination(key)
+	defer unlockDestination(key)
+
+	index := c.linkIndex()
+
+	if link, ok := index.get(key); ok {
+		return &link, false, nil
+	}
+
+	found, err := c.findShortLink(normalized, host)
+	if err != nil {
+		return nil, false, err
+	}
+	if found != nil {
+		index.put(key, *found)
+		return found, false, nil
+	}
+
+	created, err := c.CreateShortLink(reqData)
+	if err != nil {
+		return nil, false, err
+	}
+	index.put(key, *created)
+	return created, true, nil
+}
+
+// errLinkFound stops the search in findShortLink once a match is seen.
+type errLinkFound struct{}
+
+func (errLinkFound) Error() string { return "link found" }
+
+// findShortLink searches for the destination's host and path, which every
+// equivalent long URL contains whatever its query order or host case, and
+// matches the normalized URLs on the client.
+func (c *Client) findShortLink(normalized, host string) (*ShortLink, error) {
+	parsed, err := url.Parse(normalized)
+	if err != nil {
+		return nil, err
+	}
+	search := parsed.Hostname() + strings.TrimRight(parsed.Path, "/")
+
+	var found *ShortLink
+	err = c.EachShortLink(ListShortLinksOptions{Search: search}, func(link ShortLink) error {
+		candidate, err := NormalizeLongURL(link.LongURL)
+		if err != nil || candidate != normalized || domainHost(link.Domain) != host {
+			return nil
+		}
+		found = &link
+		return errLinkFound{}
+	})
+	if _, ok := err.(errLinkFound); ok {
+		return found, nil
+	}
+	return nil, err
+}
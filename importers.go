@@ -0,0 +1,347 @@
+package tly
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strings"
+)
+
+// =====================
+// Shortener Importers
+// =====================
+
+// Sources recognised in ImportedLink.Source.
+const (
+	ImportSourceBitly     = "bitly"
+	ImportSourceRebrandly = "rebrandly"
+)
+
+// ImportedLink is a link read from another shortener's export.
+type ImportedLink struct {
+	Source         string
+	SourceShortURL string
+	// Link holds the destination, the original back-half and the title as
+	// description.
+	Link     BulkShortenLink
+	TagNames []string
+}
+
+type importColumns struct {
+	shortURL []string
+	backhalf []string
+	longURL  []string
+	title    []string
+	tags     []string
+}
+
+var bitlyColumns = importColumns{
+	shortURL: []string{"bitlink", "link", "short_url", "short_link", "custom_bitlink"},
+	longURL:  []string{"long_url", "destination", "original_url"},
+	title:    []string{"title"},
+	tags:     []string{"tags"},
+}
+
+var rebrandlyColumns = importColumns{
+	shortURL: []string{"shorturl", "short_url", "short_link", "link"},
+	backhalf: []string{"slashtag"},
+	longURL:  []string{"destination", "long_url", "destination_url"},
+	title:    []string{"title"},
+	tags:     []string{"tags"},
+}
+
+// ParseBitlyCSV parses a Bitly CSV export.
+func ParseBitlyCSV(r io.Reader) ([]ImportedLink, error) {
+	return parseImportCSV(r, ImportSourceBitly, bitlyColumns)
+}
+
+// ParseRebrandlyCSV parses a Rebrandly CSV export.
+func ParseRebrandlyCSV(r io.Reader) ([]ImportedLink, error) {
+	return parseImportCSV(r, ImportSourceRebrandly, rebrandlyColumns)
+}
+
+func normalizeHeader(name string) string {
+	name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+}
+
+func parseImportCSV(r io.Reader, source string, columns importColumns) ([]ImportedLink, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s csv header: %v", source, err)
+	}
+	positions := map[string]int{}
+	for i, name := range header {
+		positions[normalizeHeader(name)] = i
+	}
+	find := func(aliases []string) int {
+		for _, alias := range aliases {
+			if i, ok := positions[alias]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+	shortCol, backhalfCol, longCol := find(columns.shortURL), find(columns.backhalf), find(columns.longURL)
+	titleCol, tagsCol := find(columns.title), find(columns.tags)
+	if longCol < 0 {
+		return nil, fmt.Errorf("%s csv has no destination column", source)
+	}
+
+	var links []ImportedLink
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", row, err)
+		}
+		cell := func(i int) string {
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		if cell(longCol) == "" {
+			continue
+		}
+		links = append(links, newImportedLink(source, cell(shortCol), cell(backhalfCol), cell(longCol), cell(titleCol), splitTagNames(cell(tagsCol))))
+	}
+	return links, nil
+}
+
+// ParseBitlyJSON parses a Bitly JSON export: either a list of bitlinks or an
+// object with a "links" list, as returned by the Bitly API.
+func ParseBitlyJSON(r io.Reader) ([]ImportedLink, error) {
+	type bitlink struct {
+		Link           string   `json:"link"`
+		ID             string   `json:"id"`
+		LongURL        string   `json:"long_url"`
+		Title          string   `json:"title"`
+		Tags           []string `json:"tags"`
+		CustomBitlinks []string `json:"custom_bitlinks"`
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var items []bitlink
+	if err := json.Unmarshal(data, &items); err != nil {
+		var wrapped struct {
+			Links []bitlink `json:"links"`
+		}
+		if err := json.Unmarshal(data, &wrapped); err != nil {
+			return nil, fmt.Errorf("unable to decode bitly export")
+		}
+		items = wrapped.Links
+	}
+
+	links := make([]ImportedLink, 0, len(items))
+	for _, item := range items {
+		shortURL := item.Link
+		if shortURL == "" {
+			shortURL = item.ID
+		}
+		if len(item.CustomBitlinks) > 0 {
+			shortURL = item.CustomBitlinks[0]
+		}
+		links = append(links, newImportedLink(ImportSourceBitly, shortURL, "", item.LongURL, item.Title, item.Tags))
+	}
+	return links, nil
+}
+
+// ParseRebrandlyJSON parses a Rebrandly JSON export: a list of links as
+// returned by the Rebrandly API.
+func ParseRebrandlyJSON(r io.Reader) ([]ImportedLink, error) {
+	var items []struct {
+		ShortURL    string            `json:"shortUrl"`
+		Slashtag    string            `json:"slashtag"`
+		Destination string            `json:"destination"`
+		Title       string            `json:"title"`
+		Tags        []json.RawMessage `json:"tags"`
+	}
+	if err := json.NewDecoder(r).Decode(&items); err != nil {
+		return nil, fmt.Errorf("unable to decode rebrandly export: %v", err)
+	}
+
+	links := make([]ImportedLink, 0, len(items))
+	for _, item := range items {
+		var tags []string
+		for _, raw := range item.Tags {
+			var name string
+			if err := json.Unmarshal(raw, &name); err != nil {
+				var tag struct {
+					Name string `json:"name"`
+				}
+				if err := json.Unmarshal(raw, &tag); err != nil {
+					continue
+				}
+				name = tag.Name
+			}
+			if name != "" {
+				tags = append(tags, name)
+			}
+		}
+		links = append(links, newImportedLink(ImportSourceRebrandly, item.ShortURL, item.Slashtag, item.Destination, item.Title, tags))
+	}
+	return links, nil
+}
+
+func newImportedLink(source, shortURL, backhalf, longURL, title string, tags []string) ImportedLink {
+	if shortURL != "" && !strings.Contains(shortURL, "://") {
+		shortURL = "https://" + shortURL
+	}
+	if backhalf == "" && shortURL != "" {
+		if parsed, err := url.Parse(shortURL); err == nil {
+			backhalf = strings.Trim(parsed.Path, "/")
+		}
+	}
+
+	link := ImportedLink{
+		Source:         source,
+		SourceShortURL: shortURL,
+		Link:           BulkShortenLink{LongURL: longURL},
+		TagNames:       tags,
+	}
+	if backhalf != "" {
+		link.Link.Backhalf = &backhalf
+	}
+	if title != "" {
+		link.Link.Description = &title
+	}
+	return link
+}
+
+func splitTagNames(cell string) []string {
+	var names []string
+	for _, name := range strings.FieldsFunc(cell, func(r rune) bool { return r == ',' || r == ';' || r == '|' }) {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// MigrationOptions controls MigrateLinks.
+type MigrationOptions struct {
+	// Domain is the T.LY domain the links are created on.
+	Domain string
+	// CreateMissingTags creates tags that do not exist in the account yet.
+	CreateMissingTags bool
+	// Concurrency is the number of parallel create calls.
+	Concurrency int
+}
+
+// MigrationResult is the outcome for one imported link.
+type MigrationResult struct {
+	SourceShortURL string
+	ShortURL       string
+	LongURL        string
+	// DroppedTags lists tag names that do not exist in the account and were
+	// left off the link. Always empty when CreateMissingTags is set.
+	DroppedTags []string
+	Err         error
+}
+
+// MigrationReport lists every migrated link and maps old short URLs to new ones.
+type MigrationReport struct {
+	Results []MigrationResult
+	Mapping map[string]string
+}
+
+// MigrateLinks creates a T.LY link for each imported link, keeping its
+// back-half as the short ID and its title as the description, and tagging it
+// with tags matched by name. Tags that do not exist are created when
+// CreateMissingTags is set and otherwise reported in DroppedTags.
+func (c *Client) MigrateLinks(ctx context.Context, links []ImportedLink, options MigrationOptions) (*MigrationReport, error) {
+	bound := c.WithContext(ctx)
+	var names []string
+	seen := map[string]bool{}
+	for _, link := range links {
+		for _, name := range link.TagNames {
+			key := strings.ToLower(strings.TrimSpace(name))
+			if key != "" && !seen[key] {
+				seen[key] = true
+				names = append(names, name)
+			}
+		}
+	}
+	// Unknown tags are dropped from the links that name them and reported per
+	// link rather than failing the whole migration.
+	tagIDs, err := bound.resolveTagIDs(names, options.CreateMissingTags)
+	if err != nil {
+		return nil, err
+	}
+	dropped := make([][]string, len(links))
+
+	inputs := make([]interface{}, len(links))
+	for i, link := range links {
+		reqData := ShortLinkCreateRequest{
+			LongURL:          link.Link.LongURL,
+			ShortID:          link.Link.Backhalf,
+			Domain:           options.Domain,
+			Description:      link.Link.Description,
+			Password:         link.Link.Password,
+			ExpireAtDatetime: link.Link.ExpireAtDatetime,
+			ExpireAtViews:    link.Link.ExpireAtViews,
+		}
+		for _, name := range link.TagNames {
+			key := strings.ToLower(strings.TrimSpace(name))
+			if id, ok := tagIDs[key]; ok {
+				reqData.Tags = append(reqData.Tags, id)
+			} else if key != "" {
+				dropped[i] = append(dropped[i], name)
+			}
+		}
+		reqData.Tags = uniqueSortedIDs(reqData.Tags)
+		inputs[i] = reqData
+	}
+	results, batchErr := c.RunBatch(ctx, inputs, func(c *Client, input interface{}) (interface{}, error) {
+		return c.CreateShortLink(input.(ShortLinkCreateRequest))
+	}, BatchOptions{Concurrency: options.Concurrency})
+
+	report := &MigrationReport{Mapping: map[string]string{}}
+	for i, result := range results {
+		migrated := MigrationResult{
+			SourceShortURL: links[i].SourceShortURL,
+			LongURL:        links[i].Link.LongURL,
+			DroppedTags:    dropped[i],
+			Err:            result.Err,
+		}
+		if created, ok := result.Output.(*ShortLink); ok && created != nil {
+			migrated.ShortURL = created.ShortURL
+			if migrated.SourceShortURL != "" {
+				report.Mapping[migrated.SourceShortURL] = created.ShortURL
+			}
+		}
+		report.Results = append(report.Results, migrated)
+	}
+	return report, batchErr
+}
+
+// WriteRedirectMapping writes the migration results as CSV with the columns
+// old_short_url, new_short_url, long_url and error, for redirect audits.
+func WriteRedirectMapping(w io.Writer, report *MigrationReport) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"old_short_url", "new_short_url", "long_url", "error"}); err != nil {
+		return err
+	}
+	for _, result := range report.Results {
+		errText := ""
+		if result.Err != nil {
+			errText = result.Err.Error()
+		}
+		if err := writer.Write([]string{result.SourceShortURL, result.ShortURL, result.LongURL, errText}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,232 @@
+package tly
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// =====================
+// Link Rot Checker
+// =====================
+
+// Destination health classes reported by CheckLinkRot.
+const (
+	LinkHealthOK          = "ok"
+	LinkHealthRedirect    = "redirect"
+	LinkHealthClientError = "client_error"
+	LinkHealthServerError = "server_error"
+	LinkHealthUnreachable = "unreachable"
+)
+
+// LinkCheckOptions controls CheckLinkRot.
+type LinkCheckOptions struct {
+	// Filter is passed to the list endpoint.
+	Filter ListShortLinksOptions
+	// Concurrency is the number of destinations probed at once.
+	Concurrency int
+	// PerHostInterval is the minimum time between probes of the same host.
+	// Defaults to one second.
+	PerHostInterval time.Duration
+	// Timeout bounds each probe. Defaults to ten seconds.
+	Timeout time.Duration
+	// HTTPClient is used for probes. It is copied so redirects are reported
+	// instead of followed. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+	// UserAgent is sent with every probe when set.
+	UserAgent string
+	// BrokenTagID, when set, is added to the tags of broken links.
+	BrokenTagID int
+	// BrokenRedirectURL, when set, becomes the long URL of broken links.
+	BrokenRedirectURL string
+}
+
+// LinkCheckResult is the probe outcome for one link.
+type LinkCheckResult struct {
+	Link       ShortLink
+	Status     string
+	StatusCode int
+	Location   string
+	Err        error
+	// Updated reports whether the link was tagged or redirected.
+	Updated   bool
+	UpdateErr error
+}
+
+// Broken reports whether the destination returned an error or could not be reached.
+func (r LinkCheckResult) Broken() bool {
+	return r.Status == LinkHealthClientError || r.Status == LinkHealthServerError || r.Status == LinkHealthUnreachable
+}
+
+// CheckLinkRot probes the destination of every link matching options.Filter
+// with HEAD, falling back to GET when HEAD fails or is rejected, and
+// classifies the response. When BrokenTagID or BrokenRedirectURL is set,
+// broken links are updated through UpdateShortLink.
+func (c *Client) CheckLinkRot(ctx context.Context, options LinkCheckOptions) ([]LinkCheckResult, error) {
+	links, err := c.WithContext(ctx).ListAllShortLinks(options.Filter)
+	if err != nil {
+		return nil, err
+	}
+	if options.BrokenRedirectURL != "" {
+		if err := validateHTTPURL(options.BrokenRedirectURL); err != nil {
+			return nil, err
+		}
+	}
+
+	prober := newLinkProber(options)
+	inputs := make([]interface{}, len(links))
+	for i, link := range links {
+		inputs[i] = link
+	}
+	batch, batchErr := c.RunBatch(ctx, inputs, func(c *Client, input interface{}) (interface{}, error) {
+		link := input.(ShortLink)
+		result := prober.check(ctx, link)
+		if result.Broken() && (options.BrokenTagID > 0 || options.BrokenRedirectURL != "") {
+			result.UpdateErr = c.markBrokenLink(link, options)
+			result.Updated = result.UpdateErr == nil
+		}
+		return result, nil
+	}, BatchOptions{Concurrency: options.Concurrency})
+
+	results := make([]LinkCheckResult, 0, len(batch))
+	for _, item := range batch {
+		result, ok := item.Output.(LinkCheckResult)
+		if !ok {
+			result = LinkCheckResult{Link: item.Input.(ShortLink), Err: item.Err}
+		}
+		results = append(results, result)
+	}
+	return results, batchErr
+}
+
+func (c *Client) markBrokenLink(link ShortLink, options LinkCheckOptions) error {
+	reqData := ShortLinkUpdateRequest{
+		ShortURL: link.ShortURL,
+		LongURL:  link.LongURL,
+	}
+	if options.BrokenRedirectURL != "" {
+		reqData.LongURL = options.BrokenRedirectURL
+	}
+	if options.BrokenTagID > 0 {
+		reqData.Tags = uniqueSortedIDs(append(link.TagIDs(), options.BrokenTagID))
+	}
+	_, err := c.UpdateShortLink(reqData)
+	return err
+}
+
+type linkProber struct {
+	client    *http.Client
+	interval  time.Duration
+	userAgent string
+
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+func newLinkProber(options LinkCheckOptions) *linkProber {
+	client := http.Client{}
+	if options.HTTPClient != nil {
+		client = *options.HTTPClient
+	}
+	client.Timeout = options.Timeout
+	if client.Timeout <= 0 {
+		client.Timeout = 10 * time.Second
+	}
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	interval := options.PerHostInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return &linkProber{
+		client:    &client,
+		interval:  interval,
+		userAgent: options.UserAgent,
+		next:      map[string]time.Time{},
+	}
+}
+
+// wait blocks until host may be probed again.
+func (p *linkProber) wait(ctx context.Context, host string) error {
+	p.mu.Lock()
+	now := time.Now()
+	at := p.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	p.next[host] = at.Add(p.interval)
+	p.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *linkProber) check(ctx context.Context, link ShortLink) LinkCheckResult {
+	result := LinkCheckResult{Link: link}
+	parsed, err := url.Parse(link.LongURL)
+	if err != nil || parsed.Host == "" {
+		result.Status = LinkHealthUnreachable
+		result.Err = validateHTTPURL(link.LongURL)
+		return result
+	}
+
+	resp, err := p.probe(ctx, http.MethodHead, parsed)
+	if err != nil || resp.StatusCode >= 400 {
+		resp, err = p.probe(ctx, http.MethodGet, parsed)
+	}
+	if err != nil {
+		result.Status = LinkHealthUnreachable
+		result.Err = err
+		return result
+	}
+
+	result.StatusCode = resp.StatusCode
+	switch {
+	case resp.StatusCode >= 500:
+		result.Status = LinkHealthServerError
+	case resp.StatusCode >= 400:
+		result.Status = LinkHealthClientError
+	case resp.StatusCode >= 300:
+		result.Status = LinkHealthRedirect
+		result.Location = resp.Header.Get("Location")
+	default:
+		result.Status = LinkHealthOK
+	}
+	return result
+}
+
+// probe sends one request and discards the body.
+func (p *linkProber) probe(ctx context.Context, method string, target *url.URL) (*http.Response, error) {
+	if err := p.wait(ctx, target.Host); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if p.userAgent != "" {
+		req.Header.Set("User-Agent", p.userAgent)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+	return resp, nil
+}
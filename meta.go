@@ -0,0 +1,354 @@
+package tly
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// =====================
+// Link Meta
+// =====================
+
+// RedirectRuleType identifies what a smart redirect rule matches on.
+type RedirectRuleType string
+
+// Supported redirect rule types.
+const (
+	RedirectRuleCountry RedirectRuleType = "country"
+	RedirectRuleDevice  RedirectRuleType = "device"
+	RedirectRuleTime    RedirectRuleType = "time"
+)
+
+// Device values accepted by device redirect rules.
+const (
+	DeviceIOS     = "ios"
+	DeviceAndroid = "android"
+	DeviceWindows = "windows"
+	DeviceMac     = "mac"
+	DeviceLinux   = "linux"
+)
+
+var validDevices = map[string]bool{
+	DeviceIOS:     true,
+	DeviceAndroid: true,
+	DeviceWindows: true,
+	DeviceMac:     true,
+	DeviceLinux:   true,
+}
+
+// RedirectRule sends visitors matching a condition to a different URL.
+// Country rules use an ISO 3166-1 alpha-2 code as Value, device rules use one
+// of the Device constants, and time rules use StartAt/EndAt.
+type RedirectRule struct {
+	Type    RedirectRuleType `json:"type"`
+	Value   string           `json:"value,omitempty"`
+	URL     string           `json:"url"`
+	StartAt string           `json:"start_at,omitempty"`
+	EndAt   string           `json:"end_at,omitempty"`
+}
+
+// Validate checks a single redirect rule.
+func (r RedirectRule) Validate() error {
+	if err := validateHTTPURL(r.URL); err != nil {
+		return fmt.Errorf("redirect rule url: %v", err)
+	}
+	switch r.Type {
+	case RedirectRuleCountry:
+		if !isCountryCode(r.Value) {
+			return fmt.Errorf("invalid country code %q", r.Value)
+		}
+	case RedirectRuleDevice:
+		if !validDevices[r.Value] {
+			return fmt.Errorf("invalid device %q", r.Value)
+		}
+	case RedirectRuleTime:
+		start, err := parseAPIDateTime(r.StartAt)
+		if err != nil {
+			return fmt.Errorf("invalid start_at %q", r.StartAt)
+		}
+		end, err := parseAPIDateTime(r.EndAt)
+		if err != nil {
+			return fmt.Errorf("invalid end_at %q", r.EndAt)
+		}
+		if !end.After(start) {
+			return fmt.Errorf("end_at must be after start_at")
+		}
+	default:
+		return fmt.Errorf("unknown redirect rule type %q", r.Type)
+	}
+	return nil
+}
+
+// LinkMeta is the typed form of the meta object attached to short links.
+// It holds the SEO preview, smart redirect rules and the UTM preset applied
+// when the link was created. Keys the client does not know about are kept in
+// Extra so they survive a read/modify/write cycle.
+type LinkMeta struct {
+	Title       string
+	Description string
+	Image       string
+	SmartURLs   []RedirectRule
+	UTMPreset   *MetaUTMPreset
+	Extra       map[string]json.RawMessage
+}
+
+// MetaUTMPreset records the UTM preset applied to a link's long URL.
+type MetaUTMPreset struct {
+	ID       int    `json:"id,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Source   string `json:"source,omitempty"`
+	Medium   string `json:"medium,omitempty"`
+	Campaign string `json:"campaign,omitempty"`
+	Content  string `json:"content,omitempty"`
+	Term     string `json:"term,omitempty"`
+}
+
+type linkMetaJSON struct {
+	Title       string         `json:"title,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Image       string         `json:"image,omitempty"`
+	SmartURLs   []RedirectRule `json:"smart_urls,omitempty"`
+	UTMPreset   *MetaUTMPreset `json:"utm_preset,omitempty"`
+}
+
+var linkMetaKeys = []string{"title", "description", "image", "smart_urls", "utm_preset"}
+
+// MarshalJSON implements json.Marshaler.
+func (m LinkMeta) MarshalJSON() ([]byte, error) {
+	known, err := json.Marshal(linkMetaJSON{
+		Title:       m.Title,
+		Description: m.Description,
+		Image:       m.Image,
+		SmartURLs:   m.SmartURLs,
+		UTMPreset:   m.UTMPreset,
+	})
+	if err != nil || len(m.Extra) == 0 {
+		return known, err
+	}
+
+	merged := map[string]json.RawMessage{}
+	for k, v := range m.Extra {
+		merged[k] = v
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(known, &fields); err != nil {
+		return nil, err
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return json.Marshal(merged)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (m *LinkMeta) UnmarshalJSON(data []byte) error {
+	var known linkMetaJSON
+	if err := json.Unmarshal(data, &known); err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for _, k := range linkMetaKeys {
+		delete(fields, k)
+	}
+	if len(fields) == 0 {
+		fields = nil
+	}
+
+	*m = LinkMeta{
+		Title:       known.Title,
+		Description: known.Description,
+		Image:       known.Image,
+		SmartURLs:   known.SmartURLs,
+		UTMPreset:   known.UTMPreset,
+		Extra:       fields,
+	}
+	return nil
+}
+
+// Validate checks the SEO image URL and every redirect rule.
+func (m LinkMeta) Validate() error {
+	if m.Image != "" {
+		if err := validateHTTPURL(m.Image); err != nil {
+			return fmt.Errorf("meta image: %v", err)
+		}
+	}
+	seen := map[string]bool{}
+	for i, rule := range m.SmartURLs {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("smart_urls[%d]: %v", i, err)
+		}
+		if rule.Type == RedirectRuleTime {
+			continue
+		}
+		key := string(rule.Type) + ":" + rule.Value
+		if seen[key] {
+			return fmt.Errorf("smart_urls[%d]: duplicate %s rule for %q", i, rule.Type, rule.Value)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// DecodeLinkMeta converts the untyped Meta value of a ShortLink or request into
+// a LinkMeta. It accepts the decoded JSON object, a JSON string, raw bytes, or
+// an existing LinkMeta. A nil or empty value yields an empty LinkMeta.
+func DecodeLinkMeta(meta interface{}) (*LinkMeta, error) {
+	var data []byte
+	switch v := meta.(type) {
+	case nil:
+		return &LinkMeta{}, nil
+	case LinkMeta:
+		return &v, nil
+	case *LinkMeta:
+		if v == nil {
+			return &LinkMeta{}, nil
+		}
+		return v, nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	case json.RawMessage:
+		data = v
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		data = encoded
+	}
+
+	trimmed := strings.TrimSpace(string(data))
+	if trimmed == "" || trimmed == "null" || trimmed == "[]" {
+		return &LinkMeta{}, nil
+	}
+	var result LinkMeta
+	if err := json.Unmarshal([]byte(trimmed), &result); err != nil {
+		return nil, fmt.Errorf("unable to decode link meta: %v", err)
+	}
+	return &result, nil
+}
+
+// LinkMeta decodes the link's Meta field into a LinkMeta.
+func (l *ShortLink) LinkMeta() (*LinkMeta, error) {
+	return DecodeLinkMeta(l.Meta)
+}
+
+// RedirectRuleBuilder builds a validated list of smart redirect rules.
+// The first invalid rule is remembered and reported by Rules or Meta.
+type RedirectRuleBuilder struct {
+	rules []RedirectRule
+	err   error
+}
+
+// NewRedirectRuleBuilder creates an empty redirect rule builder.
+func NewRedirectRuleBuilder() *RedirectRuleBuilder {
+	return &RedirectRuleBuilder{}
+}
+
+func (b *RedirectRuleBuilder) add(rule RedirectRule) *RedirectRuleBuilder {
+	if b.err != nil {
+		return b
+	}
+	if err := rule.Validate(); err != nil {
+		b.err = fmt.Errorf("rule %d: %v", len(b.rules), err)
+		return b
+	}
+	b.rules = append(b.rules, rule)
+	return b
+}
+
+// Country redirects visitors from the given ISO country code to targetURL.
+func (b *RedirectRuleBuilder) Country(code, targetURL string) *RedirectRuleBuilder {
+	return b.add(RedirectRule{
+		Type:  RedirectRuleCountry,
+		Value: strings.ToUpper(strings.TrimSpace(code)),
+		URL:   targetURL,
+	})
+}
+
+// Device redirects visitors on the given device (see the Device constants) to targetURL.
+func (b *RedirectRuleBuilder) Device(device, targetURL string) *RedirectRuleBuilder {
+	return b.add(RedirectRule{
+		Type:  RedirectRuleDevice,
+		Value: strings.ToLower(strings.TrimSpace(device)),
+		URL:   targetURL,
+	})
+}
+
+// IOS redirects iOS visitors to targetURL.
+func (b *RedirectRuleBuilder) IOS(targetURL string) *RedirectRuleBuilder {
+	return b.Device(DeviceIOS, targetURL)
+}
+
+// Android redirects Android visitors to targetURL.
+func (b *RedirectRuleBuilder) Android(targetURL string) *RedirectRuleBuilder {
+	return b.Device(DeviceAndroid, targetURL)
+}
+
+// Between redirects visitors arriving between start and end to targetURL.
+func (b *RedirectRuleBuilder) Between(start, end time.Time, targetURL string) *RedirectRuleBuilder {
+	return b.add(RedirectRule{
+		Type:    RedirectRuleTime,
+		URL:     targetURL,
+		StartAt: formatAPIDateTime(start),
+		EndAt:   formatAPIDateTime(end),
+	})
+}
+
+// Rules returns the built rules, or the first validation error.
+func (b *RedirectRuleBuilder) Rules() ([]RedirectRule, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	meta := LinkMeta{SmartURLs: b.rules}
+	if err := meta.Validate(); err != nil {
+		return nil, err
+	}
+	return append([]RedirectRule(nil), b.rules...), nil
+}
+
+// Meta returns a LinkMeta holding the built rules, ready to be used as the
+// Meta field of ShortLinkCreateRequest or ShortLinkUpdateRequest.
+func (b *RedirectRuleBuilder) Meta() (*LinkMeta, error) {
+	rules, err := b.Rules()
+	if err != nil {
+		return nil, err
+	}
+	return &LinkMeta{SmartURLs: rules}, nil
+}
+
+func isCountryCode(code string) bool {
+	if len(code) != 2 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+func validateHTTPURL(raw string) error {
+	if strings.TrimSpace(raw) == "" {
+		return fmt.Errorf("url is required")
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid url %q", raw)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("url %q must be an absolute http(s) url", raw)
+	}
+	if parsed.Host == "" {
+		return fmt.Errorf("url %q has no host", raw)
+	}
+	return nil
+}
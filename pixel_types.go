@@ -0,0 +1,97 @@
+package tly
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// =====================
+// Pixel Types
+// =====================
+
+// Pixel types supported by the API, for use as PixelType.
+const (
+	PixelTypeFacebook         = "facebook"
+	PixelTypeGoogleAnalytics  = "googleAnalytics"
+	PixelTypeGoogleTagManager = "googleTagManager"
+	PixelTypeGoogleAds        = "googleAds"
+	PixelTypeTwitter          = "twitter"
+	PixelTypeLinkedIn         = "linkedIn"
+	PixelTypeTikTok           = "tiktok"
+	PixelTypePinterest        = "pinterest"
+	PixelTypeSnapchat         = "snapchat"
+	PixelTypeQuora            = "quora"
+	PixelTypeBing             = "bing"
+	PixelTypeAdRoll           = "adroll"
+	PixelTypeReddit           = "reddit"
+)
+
+type pixelFormat struct {
+	pattern *regexp.Regexp
+	example string
+}
+
+// pixelFormats lists the ID format each provider issues.
+var pixelFormats = map[string]pixelFormat{
+	PixelTypeFacebook:         {regexp.MustCompile(`^\d{10,20}$`), "1234567890123456"},
+	PixelTypeGoogleAnalytics:  {regexp.MustCompile(`^(G-[A-Z0-9]{4,16}|UA-\d{4,10}-\d{1,4})$`), "G-ABC123XYZ9"},
+	PixelTypeGoogleTagManager: {regexp.MustCompile(`^GTM-[A-Z0-9]{4,10}$`), "GTM-ABC1234"},
+	PixelTypeGoogleAds:        {regexp.MustCompile(`^AW-\d{6,12}$`), "AW-123456789"},
+	PixelTypeTwitter:          {regexp.MustCompile(`^[a-z0-9]{5,10}$`), "o1a2b"},
+	PixelTypeLinkedIn:         {regexp.MustCompile(`^\d{4,10}$`), "1234567"},
+	PixelTypeTikTok:           {regexp.MustCompile(`^[A-Z0-9]{15,25}$`), "C1ABCDEFGHIJ23KLMNOP"},
+	PixelTypePinterest:        {regexp.MustCompile(`^\d{10,16}$`), "2612345678901"},
+	PixelTypeSnapchat:         {regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`), "a1b2c3d4-e5f6-47a8-99b0-c1d2e3f4a5b6"},
+	PixelTypeQuora:            {regexp.MustCompile(`^[0-9a-f]{32}$`), "0123456789abcdef0123456789abcdef"},
+	PixelTypeBing:             {regexp.MustCompile(`^\d{5,10}$`), "12345678"},
+	PixelTypeAdRoll:           {regexp.MustCompile(`^[A-Z0-9]{20,26}$`), "ABCDEFGHIJKLMNOPQRSTUV"},
+	PixelTypeReddit:           {regexp.MustCompile(`^[at]2_[a-z0-9]{4,16}$`), "t2_abc123"},
+}
+
+// PixelTypes returns every pixel type supported by the API, sorted.
+func PixelTypes() []string {
+	types := make([]string, 0, len(pixelFormats))
+	for pixelType := range pixelFormats {
+		types = append(types, pixelType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// ValidatePixelID checks that pixelID matches the format used by the
+// provider identified by pixelType.
+func ValidatePixelID(pixelType, pixelID string) error {
+	format, ok := pixelFormats[pixelType]
+	if !ok {
+		return fmt.Errorf("unsupported pixel type %q (supported: %s)", pixelType, strings.Join(PixelTypes(), ", "))
+	}
+	if !format.pattern.MatchString(pixelID) {
+		return fmt.Errorf("invalid %s pixel id %q (expected something like %q)", pixelType, pixelID, format.example)
+	}
+	return nil
+}
+
+func validatePixel(name, pixelType, pixelID string) error {
+	verr := &ValidationError{}
+	if strings.TrimSpace(name) == "" {
+		verr.Add("name", "The name field is required.")
+	}
+	if _, ok := pixelFormats[pixelType]; !ok {
+		verr.Add("pixel_type", fmt.Sprintf("unsupported pixel type %q", pixelType))
+	} else if err := ValidatePixelID(pixelType, pixelID); err != nil {
+		verr.Add("pixel_id", err.Error())
+	}
+	return verr.err()
+}
+
+// Validate checks the pixel type and that PixelID matches that provider's format.
+func (r PixelCreateRequest) Validate() error {
+	return validatePixel(r.Name, r.PixelType, r.PixelID)
+}
+
+// Validate checks the pixel type and that PixelID matches that provider's format.
+func (r PixelUpdateRequest) Validate() error {
+	return validatePixel(r.Name, r.PixelType, r.PixelID)
+}
@@ -0,0 +1,515 @@
+package tly
+
+import (
+	"fmt"
+)
+
+// =====================
+// QR Code Encoder
+// =====================
+
+// QRLevel is the error correction level of a generated QR code. Higher levels
+// survive more damage (or a larger logo) at the cost of a denser code.
+type QRLevel string
+
+// Error correction levels, recovering roughly 7%, 15%, 25% and 30% of the code.
+const (
+	QRLevelL QRLevel = "L"
+	QRLevelM QRLevel = "M"
+	QRLevelQ QRLevel = "Q"
+	QRLevelH QRLevel = "H"
+)
+
+type qrLevelInfo struct {
+	index      int // row in the capacity tables
+	formatBits int
+}
+
+var qrLevels = map[QRLevel]qrLevelInfo{
+	QRLevelL: {0, 1},
+	QRLevelM: {1, 0},
+	QRLevelQ: {2, 3},
+	QRLevelH: {3, 2},
+}
+
+// qrECCCodewordsPerBlock and qrNumECCBlocks are indexed by level then version
+// (index 0 is unused), as listed in ISO/IEC 18004 table 9.
+var qrECCCodewordsPerBlock = [4][41]int{
+	{-1, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+	{-1, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+}
+
+var qrNumECCBlocks = [4][41]int{
+	{-1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+	{-1, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+	{-1, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+	{-1, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+}
+
+// Penalty weights used to pick a mask.
+const (
+	qrPenaltyRun     = 3
+	qrPenaltyBlock   = 3
+	qrPenaltyFinder  = 40
+	qrPenaltyBalance = 10
+)
+
+// QRMatrix is an encoded QR code: a square grid of dark and light modules,
+// without quiet zone.
+type QRMatrix struct {
+	Version int
+	Level   QRLevel
+	size    int
+	modules []bool
+	// isFunction marks finder, timing, alignment and format modules, which
+	// are excluded from masking.
+	isFunction []bool
+}
+
+// Size returns the number of modules on each side.
+func (m *QRMatrix) Size() int {
+	return m.size
+}
+
+// Dark reports whether the module at column x, row y is dark.
+// Coordinates outside the grid are light.
+func (m *QRMatrix) Dark(x, y int) bool {
+	if x < 0 || y < 0 || x >= m.size || y >= m.size {
+		return false
+	}
+	return m.modules[y*m.size+x]
+}
+
+// isFinder reports whether the module belongs to one of the three finder
+// patterns, and whether it is in the 3x3 centre of that pattern.
+func (m *QRMatrix) isFinder(x, y int) (finder, centre bool) {
+	for _, c := range [][2]int{{3, 3}, {m.size - 4, 3}, {3, m.size - 4}} {
+		dx, dy := qrAbs(x-c[0]), qrAbs(y-c[1])
+		if dx <= 3 && dy <= 3 {
+			return true, dx <= 1 && dy <= 1
+		}
+	}
+	return false, false
+}
+
+// EncodeQR encodes content in byte mode using the smallest version (1-40)
+// that fits at the given level. An empty level means QRLevelM.
+func EncodeQR(content string, level QRLevel) (*QRMatrix, error) {
+	if level == "" {
+		level = QRLevelM
+	}
+	info, ok := qrLevels[level]
+	if !ok {
+		return nil, fmt.Errorf("unsupported qr error correction level %q", level)
+	}
+
+	data := []byte(content)
+	version := 0
+	for v := 1; v <= 40; v++ {
+		countBits := 8
+		if v >= 10 {
+			countBits = 16
+		}
+		if len(data) < 1<<uint(countBits) && 4+countBits+8*len(data) <= qrDataCodewords(v, info.index)*8 {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, fmt.Errorf("content of %d bytes is too long for a qr code at level %s", len(data), level)
+	}
+
+	m := &QRMatrix{Version: version, Level: level, size: version*4 + 17}
+	m.modules = make([]bool, m.size*m.size)
+	m.isFunction = make([]bool, m.size*m.size)
+	m.drawFunctionPatterns()
+	m.drawCodewords(qrAddECCAndInterleave(qrDataBits(data, version, info.index), version, info.index))
+
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		m.applyMask(mask)
+		m.drawFormatBits(info.formatBits, mask)
+		if penalty := m.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		m.applyMask(mask) // masking is its own inverse
+	}
+	m.applyMask(best)
+	m.drawFormatBits(info.formatBits, best)
+	return m, nil
+}
+
+// qrDataBits builds the byte-mode bit stream, padded to the version's capacity.
+func qrDataBits(data []byte, version, level int) []byte {
+	var bits qrBitBuffer
+	bits.append(0x4, 4)
+	if version >= 10 {
+		bits.append(len(data), 16)
+	} else {
+		bits.append(len(data), 8)
+	}
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+
+	capacity := qrDataCodewords(version, level) * 8
+	terminator := capacity - len(bits)
+	if terminator > 4 {
+		terminator = 4
+	}
+	bits.append(0, terminator)
+	bits.append(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+	return bits.bytes()
+}
+
+type qrBitBuffer []bool
+
+func (b *qrBitBuffer) append(value, count int) {
+	for i := count - 1; i >= 0; i-- {
+		*b = append(*b, (value>>uint(i))&1 != 0)
+	}
+}
+
+func (b qrBitBuffer) bytes() []byte {
+	out := make([]byte, len(b)/8)
+	for i, bit := range b {
+		if bit {
+			out[i/8] |= 0x80 >> uint(i%8)
+		}
+	}
+	return out
+}
+
+// qrRawDataModules is the number of modules available for data and error
+// correction in a version, after function patterns.
+func qrRawDataModules(version int) int {
+	result := (16*version+128)*version + 64
+	if version >= 2 {
+		align := version/7 + 2
+		result -= (25*align-10)*align - 55
+		if version >= 7 {
+			result -= 36
+		}
+	}
+	return result
+}
+
+func qrDataCodewords(version, level int) int {
+	return qrRawDataModules(version)/8 - qrECCCodewordsPerBlock[level][version]*qrNumECCBlocks[level][version]
+}
+
+// qrAddECCAndInterleave splits data into blocks, appends Reed-Solomon error
+// correction to each, and interleaves the blocks.
+func qrAddECCAndInterleave(data []byte, version, level int) []byte {
+	numBlocks := qrNumECCBlocks[level][version]
+	eccLen := qrECCCodewordsPerBlock[level][version]
+	rawCodewords := qrRawDataModules(version) / 8
+	numShortBlocks := numBlocks - rawCodewords%numBlocks
+	shortBlockLen := rawCodewords / numBlocks
+
+	divisor := qrReedSolomonDivisor(eccLen)
+	blocks := make([][]byte, numBlocks)
+	for i, k := 0, 0; i < numBlocks; i++ {
+		n := shortBlockLen - eccLen
+		if i >= numShortBlocks {
+			n++
+		}
+		block := append([]byte(nil), data[k:k+n]...)
+		k += n
+		ecc := qrReedSolomonRemainder(block, divisor)
+		if i < numShortBlocks {
+			// Pad short blocks so every block has the same layout.
+			block = append(block, 0)
+		}
+		blocks[i] = append(block, ecc...)
+	}
+
+	result := make([]byte, 0, rawCodewords)
+	for i := range blocks[0] {
+		for j, block := range blocks {
+			if i != shortBlockLen-eccLen || j >= numShortBlocks {
+				result = append(result, block[i])
+			}
+		}
+	}
+	return result
+}
+
+func qrReedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = qrGFMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = qrGFMultiply(root, 0x02)
+	}
+	return result
+}
+
+func qrReedSolomonRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, d := range divisor {
+			result[i] ^= qrGFMultiply(d, factor)
+		}
+	}
+	return result
+}
+
+// qrGFMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1.
+func qrGFMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>uint(i))&1) * int(x)
+	}
+	return byte(z)
+}
+
+func (m *QRMatrix) setFunction(x, y int, dark bool) {
+	m.modules[y*m.size+x] = dark
+	m.isFunction[y*m.size+x] = true
+}
+
+func (m *QRMatrix) drawFunctionPatterns() {
+	for i := 0; i < m.size; i++ {
+		m.setFunction(6, i, i%2 == 0)
+		m.setFunction(i, 6, i%2 == 0)
+	}
+
+	for _, c := range [][2]int{{3, 3}, {m.size - 4, 3}, {3, m.size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := c[0]+dx, c[1]+dy
+				if x < 0 || y < 0 || x >= m.size || y >= m.size {
+					continue
+				}
+				dist := qrMax(qrAbs(dx), qrAbs(dy))
+				m.setFunction(x, y, dist != 2 && dist != 4)
+			}
+		}
+	}
+
+	positions := qrAlignmentPositions(m.Version)
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			// Skip the three corners occupied by finder patterns.
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					m.setFunction(x+dx, y+dy, qrMax(qrAbs(dx), qrAbs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	// Reserve the format areas; the real bits are drawn once a mask is chosen.
+	m.drawFormatBits(0, 0)
+	m.drawVersionBits()
+}
+
+func qrAlignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+	count := version/7 + 2
+	step := 26
+	if version != 32 {
+		step = (version*4 + count*2 + 1) / (count*2 - 2) * 2
+	}
+	positions := make([]int, count)
+	positions[0] = 6
+	for i, pos := count-1, version*4+10; i >= 1; i, pos = i-1, pos-step {
+		positions[i] = pos
+	}
+	return positions
+}
+
+func (m *QRMatrix) drawFormatBits(levelBits, mask int) {
+	data := levelBits<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return (bits>>uint(i))&1 != 0 }
+
+	for i := 0; i <= 5; i++ {
+		m.setFunction(8, i, bit(i))
+	}
+	m.setFunction(8, 7, bit(6))
+	m.setFunction(8, 8, bit(7))
+	m.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		m.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		m.setFunction(m.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		m.setFunction(8, m.size-15+i, bit(i))
+	}
+	m.setFunction(8, m.size-8, true)
+}
+
+func (m *QRMatrix) drawVersionBits() {
+	if m.Version < 7 {
+		return
+	}
+	rem := m.Version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	bits := m.Version<<12 | rem
+	for i := 0; i < 18; i++ {
+		dark := (bits>>uint(i))&1 != 0
+		a, b := m.size-11+i%3, i/3
+		m.setFunction(a, b, dark)
+		m.setFunction(b, a, dark)
+	}
+}
+
+// drawCodewords places the data in the two-column zigzag order, skipping
+// function modules.
+func (m *QRMatrix) drawCodewords(data []byte) {
+	i := 0
+	for right := m.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < m.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x, y := right-j, vert
+				if upward {
+					y = m.size - 1 - vert
+				}
+				if !m.isFunction[y*m.size+x] && i < len(data)*8 {
+					m.modules[y*m.size+x] = (data[i>>3]>>uint(7-(i&7)))&1 != 0
+					i++
+				}
+			}
+		}
+	}
+}
+
+func (m *QRMatrix) applyMask(mask int) {
+	for y := 0; y < m.size; y++ {
+		for x := 0; x < m.size; x++ {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !m.isFunction[y*m.size+x] {
+				m.modules[y*m.size+x] = !m.modules[y*m.size+x]
+			}
+		}
+	}
+}
+
+// penalty scores the current grid with the four rules from the
+// specification; the mask with the lowest score is used.
+func (m *QRMatrix) penalty() int {
+	total := 0
+	dark := 0
+	finderA := []bool{true, false, true, true, true, false, true, false, false, false, false}
+	finderB := []bool{false, false, false, false, true, false, true, true, true, false, true}
+
+	for pass := 0; pass < 2; pass++ {
+		at := func(a, b int) bool {
+			if pass == 0 {
+				return m.Dark(b, a)
+			}
+			return m.Dark(a, b)
+		}
+		for a := 0; a < m.size; a++ {
+			run := 1
+			for b := 1; b <= m.size; b++ {
+				if b < m.size && at(a, b) == at(a, b-1) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					total += qrPenaltyRun + run - 5
+				}
+				run = 1
+			}
+			for b := 0; b+len(finderA) <= m.size; b++ {
+				matchA, matchB := true, true
+				for k := range finderA {
+					v := at(a, b+k)
+					matchA = matchA && v == finderA[k]
+					matchB = matchB && v == finderB[k]
+				}
+				if matchA {
+					total += qrPenaltyFinder
+				}
+				if matchB {
+					total += qrPenaltyFinder
+				}
+			}
+		}
+	}
+
+	for y := 0; y < m.size; y++ {
+		for x := 0; x < m.size; x++ {
+			v := m.Dark(x, y)
+			if v {
+				dark++
+			}
+			if x+1 < m.size && y+1 < m.size && v == m.Dark(x+1, y) && v == m.Dark(x, y+1) && v == m.Dark(x+1, y+1) {
+				total += qrPenaltyBlock
+			}
+		}
+	}
+
+	count := m.size * m.size
+	k := (qrAbs(dark*20-count*10)+count-1)/count - 1
+	return total + k*qrPenaltyBalance
+}
+
+func qrAbs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func qrMax(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,414 @@
+package tly
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The decoder below reads a QRMatrix back the way a scanner would: format and
+// version bits, unmasking, the zigzag codeword order, Reed-Solomon syndromes
+// and the byte-mode segment. It shares only the capacity tables with the
+// encoder.
+
+var qrTestLevels = map[int]QRLevel{1: QRLevelL, 0: QRLevelM, 3: QRLevelQ, 2: QRLevelH}
+
+// qrTestAlignment lists alignment pattern centres from ISO/IEC 18004 annex E.
+var qrTestAlignment = map[int][]int{
+	1:  nil,
+	2:  {6, 18},
+	6:  {6, 34},
+	7:  {6, 22, 38},
+	10: {6, 28, 50},
+	14: {6, 26, 46, 66},
+	15: {6, 26, 48, 70},
+	32: {6, 34, 60, 86, 112, 138},
+	40: {6, 30, 58, 86, 114, 142, 170},
+}
+
+func TestQRAlignmentPositions(t *testing.T) {
+	for version, want := range qrTestAlignment {
+		if got := qrAlignmentPositions(version); !reflect.DeepEqual(got, want) {
+			t.Errorf("version %d: got %v, want %v", version, got, want)
+		}
+	}
+}
+
+func TestEncodeQRRoundTrip(t *testing.T) {
+	lengths := []int{0, 1, 17, 40, 100, 271, 500, 1000, 1273}
+	for _, level := range []QRLevel{QRLevelL, QRLevelM, QRLevelQ, QRLevelH} {
+		for _, n := range lengths {
+			content := qrTestContent(n)
+			t.Run(fmt.Sprintf("%s/%d", level, n), func(t *testing.T) {
+				m, err := EncodeQR(content, level)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := qrTestDecode(m)
+				if err != nil {
+					t.Fatalf("version %d: %v", m.Version, err)
+				}
+				if got != content {
+					t.Fatalf("version %d: decoded %q, want %q", m.Version, got, content)
+				}
+			})
+		}
+	}
+}
+
+func TestEncodeQRLargestContent(t *testing.T) {
+	// 2953 bytes is the byte-mode capacity of version 40 at level L.
+	content := qrTestContent(2953)
+	m, err := EncodeQR(content, QRLevelL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Version != 40 {
+		t.Errorf("version = %d, want 40", m.Version)
+	}
+	if got, err := qrTestDecode(m); err != nil || got != content {
+		t.Fatalf("round trip failed: %v", err)
+	}
+	if _, err := EncodeQR(content+"x", QRLevelL); err == nil {
+		t.Error("expected an error for content over capacity")
+	}
+}
+
+func TestEncodeQRSmallestVersion(t *testing.T) {
+	// Byte-mode capacities of versions 1 and 2 at each level.
+	tests := []struct {
+		level  QRLevel
+		v1, v2 int
+	}{
+		{QRLevelL, 17, 32},
+		{QRLevelM, 14, 26},
+		{QRLevelQ, 11, 20},
+		{QRLevelH, 7, 14},
+	}
+	for _, tt := range tests {
+		for n, want := range map[int]int{tt.v1: 1, tt.v1 + 1: 2, tt.v2: 2, tt.v2 + 1: 3} {
+			m, err := EncodeQR(qrTestContent(n), tt.level)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.Version != want {
+				t.Errorf("level %s, %d bytes: version %d, want %d", tt.level, n, m.Version, want)
+			}
+		}
+	}
+}
+
+func TestEncodeQRInvalidLevel(t *testing.T) {
+	if _, err := EncodeQR("x", QRLevel("X")); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
+
+// qrTestContent returns n bytes covering every byte value.
+func qrTestContent(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		b.WriteByte(byte(i*7 + i/256))
+	}
+	return b.String()
+}
+
+func qrTestDecode(m *QRMatrix) (string, error) {
+	size := m.Size()
+	version := (size - 17) / 4
+	if version < 1 || version > 40 || size != version*4+17 || version != m.Version {
+		return "", fmt.Errorf("bad size %d for version %d", size, m.Version)
+	}
+	bit := func(x, y int) int {
+		if m.Dark(x, y) {
+			return 1
+		}
+		return 0
+	}
+
+	// Format information, both copies.
+	var first, second int
+	for i := 0; i <= 5; i++ {
+		first |= bit(8, i) << uint(i)
+	}
+	first |= bit(8, 7)<<6 | bit(8, 8)<<7 | bit(7, 8)<<8
+	for i := 9; i < 15; i++ {
+		first |= bit(14-i, 8) << uint(i)
+	}
+	for i := 0; i < 8; i++ {
+		second |= bit(size-1-i, 8) << uint(i)
+	}
+	for i := 8; i < 15; i++ {
+		second |= bit(8, size-15+i) << uint(i)
+	}
+	if first != second {
+		return "", fmt.Errorf("format copies differ: %015b and %015b", first, second)
+	}
+	format := first ^ 0x5412
+	if qrTestBCHRemainder(format, 0x537, 10) != 0 {
+		return "", fmt.Errorf("format bits %015b fail the BCH check", format)
+	}
+	level, mask := qrTestLevels[format>>13], format>>10&7
+	if level != m.Level {
+		return "", fmt.Errorf("format level %s, want %s", level, m.Level)
+	}
+	if bit(8, size-8) != 1 {
+		return "", fmt.Errorf("dark module missing")
+	}
+
+	// Version information, both copies.
+	if version >= 7 {
+		var a, b int
+		for i := 0; i < 18; i++ {
+			a |= bit(size-11+i%3, i/3) << uint(i)
+			b |= bit(i/3, size-11+i%3) << uint(i)
+		}
+		if a != b || a>>12 != version || qrTestBCHRemainder(a, 0x1F25, 12) != 0 {
+			return "", fmt.Errorf("bad version bits %018b / %018b", a, b)
+		}
+	}
+
+	function, err := qrTestFunctionModules(m, version)
+	if err != nil {
+		return "", err
+	}
+
+	// Read the codewords in zigzag order, unmasking as we go.
+	var raw []byte
+	var cur byte
+	n := 0
+	for right := size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < size; vert++ {
+			y := vert
+			if (right+1)&2 == 0 {
+				y = size - 1 - vert
+			}
+			for x := right; x > right-2; x-- {
+				if function[y*size+x] {
+					continue
+				}
+				v := m.Dark(x, y) != qrTestMask(mask, x, y)
+				cur <<= 1
+				if v {
+					cur |= 1
+				}
+				if n++; n%8 == 0 {
+					raw = append(raw, cur)
+					cur = 0
+				}
+			}
+		}
+	}
+	if want := qrRawDataModules(version) / 8; len(raw) != want {
+		return "", fmt.Errorf("read %d codewords, want %d", len(raw), want)
+	}
+
+	// De-interleave and check every block's error correction.
+	li := qrLevels[level].index
+	numBlocks := qrNumECCBlocks[li][version]
+	eccLen := qrECCCodewordsPerBlock[li][version]
+	numShort := numBlocks - len(raw)%numBlocks
+	shortData := len(raw)/numBlocks - eccLen
+	blocks := make([][]byte, numBlocks)
+	k := 0
+	for i := 0; i <= shortData; i++ {
+		for j := range blocks {
+			if i < shortData || j >= numShort {
+				blocks[j] = append(blocks[j], raw[k])
+				k++
+			}
+		}
+	}
+	var data []byte
+	for _, block := range blocks {
+		data = append(data, block...)
+	}
+	for i := 0; i < eccLen; i++ {
+		for j := range blocks {
+			blocks[j] = append(blocks[j], raw[k])
+			k++
+		}
+	}
+	for j, block := range blocks {
+		for r := 0; r < eccLen; r++ {
+			if s := qrTestSyndrome(block, r); s != 0 {
+				return "", fmt.Errorf("block %d: syndrome %d is %d", j, r, s)
+			}
+		}
+	}
+
+	// Parse the byte-mode segment and check the padding.
+	pos := 0
+	read := func(count int) int {
+		v := 0
+		for i := 0; i < count; i++ {
+			v = v<<1 | int(data[pos/8]>>uint(7-pos%8)&1)
+			pos++
+		}
+		return v
+	}
+	if mode := read(4); mode != 0x4 {
+		return "", fmt.Errorf("mode %04b, want byte mode", mode)
+	}
+	countBits := 8
+	if version >= 10 {
+		countBits = 16
+	}
+	length := read(countBits)
+	if 4+countBits+8*length > len(data)*8 {
+		return "", fmt.Errorf("length %d exceeds capacity", length)
+	}
+	content := make([]byte, length)
+	for i := range content {
+		content[i] = byte(read(8))
+	}
+	for i := 0; i < 4 && pos < len(data)*8; i++ {
+		if read(1) != 0 {
+			return "", fmt.Errorf("terminator is not zero")
+		}
+	}
+	for pos%8 != 0 {
+		if read(1) != 0 {
+			return "", fmt.Errorf("bit padding is not zero")
+		}
+	}
+	for pad := 0xEC; pos < len(data)*8; pad ^= 0xEC ^ 0x11 {
+		if got := read(8); got != pad {
+			return "", fmt.Errorf("pad byte %#x, want %#x", got, pad)
+		}
+	}
+	return string(content), nil
+}
+
+// qrTestFunctionModules marks the modules that carry no data and checks the
+// finder, timing and alignment patterns drawn on them.
+func qrTestFunctionModules(m *QRMatrix, version int) ([]bool, error) {
+	size := m.Size()
+	function := make([]bool, size*size)
+	mark := func(x0, y0, w, h int) {
+		for y := y0; y < y0+h; y++ {
+			for x := x0; x < x0+w; x++ {
+				function[y*size+x] = true
+			}
+		}
+	}
+
+	// Finders with separators and format areas.
+	mark(0, 0, 9, 9)
+	mark(size-8, 0, 8, 9)
+	mark(0, size-8, 9, 8)
+	for _, c := range [][2]int{{3, 3}, {size - 4, 3}, {3, size - 4}} {
+		for dy := -3; dy <= 3; dy++ {
+			for dx := -3; dx <= 3; dx++ {
+				ring := qrAbs(dx)
+				if qrAbs(dy) > ring {
+					ring = qrAbs(dy)
+				}
+				if m.Dark(c[0]+dx, c[1]+dy) != (ring != 2) {
+					return nil, fmt.Errorf("finder at %v is wrong at %d,%d", c, dx, dy)
+				}
+			}
+		}
+	}
+
+	// Timing patterns.
+	mark(0, 6, size, 1)
+	mark(6, 0, 1, size)
+	for i := 8; i < size-8; i++ {
+		if m.Dark(i, 6) != (i%2 == 0) || m.Dark(6, i) != (i%2 == 0) {
+			return nil, fmt.Errorf("timing pattern is wrong at %d", i)
+		}
+	}
+
+	positions := qrAlignmentPositions(version)
+	if want, ok := qrTestAlignment[version]; ok && !reflect.DeepEqual(positions, want) {
+		return nil, fmt.Errorf("alignment positions %v, want %v", positions, want)
+	}
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			mark(x-2, y-2, 5, 5)
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					ring := qrAbs(dx)
+					if qrAbs(dy) > ring {
+						ring = qrAbs(dy)
+					}
+					if m.Dark(x+dx, y+dy) != (ring != 1) {
+						return nil, fmt.Errorf("alignment pattern at %d,%d is wrong", x, y)
+					}
+				}
+			}
+		}
+	}
+
+	if version >= 7 {
+		mark(size-11, 0, 3, 6)
+		mark(0, size-11, 6, 3)
+	}
+	return function, nil
+}
+
+func qrTestMask(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (y+x)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (y+x)%3 == 0
+	case 4:
+		return (y/2+x/3)%2 == 0
+	case 5:
+		return (y*x)%2+(y*x)%3 == 0
+	case 6:
+		return ((y*x)%2+(y*x)%3)%2 == 0
+	default:
+		return ((y+x)%2+(y*x)%3)%2 == 0
+	}
+}
+
+// qrTestBCHRemainder divides a codeword by the generator polynomial.
+func qrTestBCHRemainder(codeword, generator, degree int) int {
+	for i := 30; i >= degree; i-- {
+		if codeword>>uint(i)&1 != 0 {
+			codeword ^= generator << uint(i-degree)
+		}
+	}
+	return codeword
+}
+
+// qrTestSyndrome evaluates the block polynomial at alpha^r in GF(256).
+func qrTestSyndrome(block []byte, r int) byte {
+	var exp [255]byte
+	var log [256]int
+	x := 1
+	for i := range exp {
+		exp[i] = byte(x)
+		log[x] = i
+		if x <<= 1; x >= 256 {
+			x ^= 0x11D
+		}
+	}
+	mul := func(a, b byte) byte {
+		if a == 0 || b == 0 {
+			return 0
+		}
+		return exp[(log[a]+log[b])%255]
+	}
+	alpha := exp[r%255]
+	var s byte
+	for _, c := range block {
+		s = mul(s, alpha) ^ c
+	}
+	return s
+}
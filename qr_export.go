@@ -0,0 +1,207 @@
+package tly
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// =====================
+// QR Code Export
+// =====================
+
+// QRExportManifest is the name of the manifest written into QR code archives.
+const QRExportManifest = "manifest.csv"
+
+// QRExportOptions controls ExportQRCodes.
+type QRExportOptions struct {
+	// Filter is passed to the list endpoint.
+	Filter ListShortLinksOptions
+	// TagNames are resolved with ResolveTagNames and added to Filter.TagIDs.
+	TagNames []string
+	// Format is the QR code format requested from GetQRCode, such as
+	// QRFormatPNG or QRFormatSVG. Empty uses the API default.
+	Format string
+	// Concurrency is the number of parallel QR code requests.
+	Concurrency int
+	// MaxFailures stops the export once this many QR codes have failed.
+	// Zero means never stop early.
+	MaxFailures int
+	// Progress, if set, is called after each QR code is fetched with the
+	// number fetched so far. Links are listed page by page, so the total is
+	// not known in advance.
+	Progress func(done int)
+}
+
+// QRExportResult is the outcome for one link.
+type QRExportResult struct {
+	Link ShortLink
+	File string
+	Err  error
+}
+
+// QRExportReport lists the outcome for every exported link.
+type QRExportReport struct {
+	Results []QRExportResult
+	Written int
+	Failed  int
+}
+
+// ExportQRCodes writes a zip archive to w holding the QR code of every link
+// matching the options, named by short ID, plus a manifest.csv mapping each
+// file to its short and long URL. Links are processed one page at a time
+// through RunBatch. A QR code that cannot be fetched is recorded in the report
+// and left out of the archive; the archive is always finished, even when the
+// export stops early.
+func (c *Client) ExportQRCodes(ctx context.Context, w io.Writer, options QRExportOptions) (*QRExportReport, error) {
+	bound := c.WithContext(ctx)
+	filter := options.Filter
+	if len(options.TagNames) > 0 {
+		ids, err := bound.ResolveTagNames(options.TagNames, false)
+		if err != nil {
+			return nil, err
+		}
+		filter.TagIDs = append(append([]int(nil), filter.TagIDs...), ids...)
+	}
+
+	archive := zip.NewWriter(w)
+	report := &QRExportReport{}
+	var manifest [][]string
+	usedNames := map[string]bool{}
+	done, failures := 0, 0
+
+	var page []ShortLink
+	writePage := func() error {
+		inputs := make([]interface{}, len(page))
+		for i, link := range page {
+			inputs[i] = QRCodeRequest{ShortURL: link.ShortURL, Format: options.Format}
+		}
+		batchOptions := BatchOptions{Concurrency: options.Concurrency}
+		if options.MaxFailures > 0 {
+			batchOptions.MaxFailures = options.MaxFailures - failures
+		}
+		if options.Progress != nil {
+			base := done
+			batchOptions.Progress = func(n, _ int) {
+				options.Progress(base + n)
+			}
+		}
+		results, batchErr := c.RunBatch(ctx, inputs, batchGetQRImage, batchOptions)
+		for i, result := range results {
+			entry := QRExportResult{Link: page[i], Err: result.Err}
+			if entry.Err == nil {
+				img := result.Output.(*QRImage)
+				entry.File = qrExportFileName(page[i], img.Extension(), usedNames)
+				if err := writeZipEntry(archive, entry.File, img); err != nil {
+					return err
+				}
+				manifest = append(manifest, []string{entry.File, page[i].ShortURL, page[i].LongURL})
+				report.Written++
+			} else if entry.Err != batchErr {
+				failures++
+				report.Failed++
+			}
+			report.Results = append(report.Results, entry)
+		}
+		done += len(page)
+		page = page[:0]
+		return batchErr
+	}
+
+	err := bound.EachShortLink(filter, func(link ShortLink) error {
+		page = append(page, link)
+		if len(page) < exportPageSize {
+			return nil
+		}
+		return writePage()
+	})
+	if err == nil && len(page) > 0 {
+		err = writePage()
+	}
+
+	if manifestErr := writeQRManifest(archive, manifest); manifestErr != nil && err == nil {
+		err = manifestErr
+	}
+	if closeErr := archive.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	return report, err
+}
+
+// ExportQRCodesFile is ExportQRCodes writing to a new file at path.
+func (c *Client) ExportQRCodesFile(ctx context.Context, path string, options QRExportOptions) (*QRExportReport, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	report, err := c.ExportQRCodes(ctx, f, options)
+	if closeErr := f.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	return report, err
+}
+
+func batchGetQRImage(c *Client, input interface{}) (interface{}, error) {
+	req, ok := input.(QRCodeRequest)
+	if !ok {
+		return nil, fmt.Errorf("batch input must be a QRCodeRequest, got %T", input)
+	}
+	return c.GetQRImage(req)
+}
+
+// qrExportFileName names a QR code after the link's short ID, adding a
+// numeric suffix when the same short ID appears on several domains.
+func qrExportFileName(link ShortLink, ext string, used map[string]bool) string {
+	base := link.ShortID
+	if base == "" {
+		base = path.Base(strings.TrimRight(link.ShortURL, "/"))
+	}
+	base = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' {
+			return '_'
+		}
+		return r
+	}, base)
+
+	name := base + ext
+	for n := 2; used[strings.ToLower(name)]; n++ {
+		name = fmt.Sprintf("%s-%d%s", base, n, ext)
+	}
+	used[strings.ToLower(name)] = true
+	return name
+}
+
+func writeZipEntry(archive *zip.Writer, name string, img *QRImage) error {
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()}
+	if img.Format == QRFormatPNG || img.Format == QRFormatJPEG {
+		// Already compressed.
+		header.Method = zip.Store
+	}
+	entry, err := archive.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = img.WriteTo(entry)
+	return err
+}
+
+func writeQRManifest(archive *zip.Writer, rows [][]string) error {
+	entry, err := archive.CreateHeader(&zip.FileHeader{Name: QRExportManifest, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(entry)
+	if err := writer.Write([]string{"file", "short_url", "long_url"}); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
@@ -0,0 +1,170 @@
+package tly
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// =====================
+// QR Code Images
+// =====================
+
+// QR code formats, for use as QRCodeRequest.Format and QRImage.Format.
+const (
+	QRFormatPNG  = "png"
+	QRFormatSVG  = "svg"
+	QRFormatJPEG = "jpeg"
+	QRFormatEPS  = "eps"
+)
+
+// QROutputBase64 asks GetQRCode for a base64 payload instead of image bytes.
+const QROutputBase64 = "base64"
+
+var qrFormatContentTypes = map[string]string{
+	QRFormatPNG:  "image/png",
+	QRFormatSVG:  "image/svg+xml",
+	QRFormatJPEG: "image/jpeg",
+	QRFormatEPS:  "application/postscript",
+}
+
+var qrFormatExtensions = map[string]string{
+	QRFormatPNG:  ".png",
+	QRFormatSVG:  ".svg",
+	QRFormatJPEG: ".jpg",
+	QRFormatEPS:  ".eps",
+}
+
+// QRImage is a decoded QR code image.
+type QRImage struct {
+	ContentType string
+	Format      string
+	Data        []byte
+}
+
+// GetQRImage fetches a QR code with GetQRCode and decodes it into image
+// bytes. Base64 payloads, data URIs and JSON-wrapped payloads are decoded
+// automatically, and the format is detected from the image itself.
+func (c *Client) GetQRImage(reqData QRCodeRequest) (*QRImage, error) {
+	payload, err := c.GetQRCode(reqData)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeQRImage(payload)
+}
+
+// DecodeQRImage detects the format of a QR code payload as returned by
+// GetQRCode, decoding base64 and data URI forms first.
+func DecodeQRImage(payload []byte) (*QRImage, error) {
+	if format := detectQRFormat(payload); format != "" {
+		return newQRImage(format, payload), nil
+	}
+
+	text := strings.TrimSpace(string(payload))
+	if strings.HasPrefix(text, "{") || strings.HasPrefix(text, `"`) {
+		unwrapped, err := unwrapQRPayload([]byte(text))
+		if err != nil {
+			return nil, err
+		}
+		text = strings.TrimSpace(unwrapped)
+	}
+	if strings.HasPrefix(text, "data:") {
+		comma := strings.IndexByte(text, ',')
+		if comma < 0 || !strings.HasSuffix(text[:comma], ";base64") {
+			return nil, fmt.Errorf("unsupported qr code data uri")
+		}
+		text = text[comma+1:]
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(text), ""))
+	if err != nil {
+		return nil, fmt.Errorf("unrecognized qr code payload: %v", err)
+	}
+	format := detectQRFormat(data)
+	if format == "" {
+		return nil, fmt.Errorf("unrecognized qr code image format")
+	}
+	return newQRImage(format, data), nil
+}
+
+// unwrapQRPayload extracts the encoded image from a JSON string, or from a JSON
+// object's qr_code_base64, base64, qr_code or image field, checked in that
+// order after descending through any "data" wrappers.
+func unwrapQRPayload(data []byte) (string, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return "", fmt.Errorf("unable to decode qr code payload: %v", err)
+	}
+	for {
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case map[string]interface{}:
+			if inner, ok := v["data"]; ok {
+				value = inner
+				continue
+			}
+			for _, key := range []string{"qr_code_base64", "base64", "qr_code", "image"} {
+				if s, ok := v[key].(string); ok {
+					return s, nil
+				}
+			}
+		}
+		return "", fmt.Errorf("qr code payload has no image data")
+	}
+}
+
+func newQRImage(format string, data []byte) *QRImage {
+	return &QRImage{ContentType: qrFormatContentTypes[format], Format: format, Data: data}
+}
+
+// detectQRFormat recognizes an image by its leading bytes.
+func detectQRFormat(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return QRFormatPNG
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return QRFormatJPEG
+	case bytes.HasPrefix(data, []byte("%!PS")), bytes.HasPrefix(data, []byte{0xC5, 0xD0, 0xD3, 0xC6}):
+		return QRFormatEPS
+	}
+	head := data
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	head = bytes.TrimSpace(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf")))
+	if bytes.HasPrefix(head, []byte("<")) && bytes.Contains(bytes.ToLower(head), []byte("<svg")) {
+		return QRFormatSVG
+	}
+	return ""
+}
+
+// Extension returns the file extension for the image's format, such as ".png".
+func (img *QRImage) Extension() string {
+	return qrFormatExtensions[img.Format]
+}
+
+// WriteTo writes the image bytes to w. It implements io.WriterTo.
+func (img *QRImage) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(img.Data)
+	return int64(n), err
+}
+
+// SaveFile writes the image to path, adding the format's extension when path
+// does not already end with it. It returns the path written.
+func (img *QRImage) SaveFile(path string) (string, error) {
+	ext := img.Extension()
+	current := strings.ToLower(filepath.Ext(path))
+	if ext != "" && current != ext && !(img.Format == QRFormatJPEG && current == ".jpeg") {
+		path += ext
+	}
+	if err := ioutil.WriteFile(path, img.Data, 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,184 @@
+package tly
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"  // register GIF for image.Decode
+	_ "image/jpeg" // register JPEG for image.Decode
+	"image/png"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// =====================
+// QR Code Logos
+// =====================
+
+// Defaults applied by EncodeQRLogo.
+const (
+	DefaultQRLogoMaxBytes     = 2 << 20
+	DefaultQRLogoMaxDimension = 1024
+)
+
+// maxQRLogoSourceBytes bounds the file read when resizing, and
+// maxQRLogoSourcePixels rejects huge images before they are decoded.
+const (
+	maxQRLogoSourceBytes  = 32 << 20
+	maxQRLogoSourcePixels = 40000000
+)
+
+// ErrUnsupportedQRLogo is returned, wrapped, for files that are not a PNG,
+// JPEG or GIF image.
+var ErrUnsupportedQRLogo = errors.New("unsupported qr code logo")
+
+var qrLogoContentTypes = map[string]string{
+	"png":  "image/png",
+	"jpeg": "image/jpeg",
+	"gif":  "image/gif",
+}
+
+// QRLogoOptions controls how a logo is checked and prepared for upload.
+type QRLogoOptions struct {
+	// MaxBytes limits the size of the uploaded image. Defaults to DefaultQRLogoMaxBytes.
+	MaxBytes int64
+	// MinDimension rejects images narrower or shorter than this many pixels.
+	MinDimension int
+	// MaxDimension is the largest width or height accepted. Defaults to
+	// DefaultQRLogoMaxDimension.
+	MaxDimension int
+	// Resize scales larger images down to MaxDimension, re-encoded as PNG,
+	// instead of rejecting them.
+	Resize bool
+}
+
+// EncodeQRLogo reads a PNG, JPEG or GIF image from r, checks its format and
+// dimensions, optionally resizes it, and returns it as a base64 data URI
+// ready for QRCodeUpdateRequest.Image.
+func EncodeQRLogo(r io.Reader, options QRLogoOptions) (string, error) {
+	if options.MaxBytes <= 0 {
+		options.MaxBytes = DefaultQRLogoMaxBytes
+	}
+	if options.MaxDimension <= 0 {
+		options.MaxDimension = DefaultQRLogoMaxDimension
+	}
+
+	limit := options.MaxBytes
+	if options.Resize && limit < maxQRLogoSourceBytes {
+		limit = maxQRLogoSourceBytes
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(data)) > limit {
+		return "", fmt.Errorf("qr code logo is larger than %d bytes", limit)
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("%w: not a PNG, JPEG or GIF image", ErrUnsupportedQRLogo)
+	}
+	contentType, ok := qrLogoContentTypes[format]
+	if !ok {
+		return "", fmt.Errorf("%w: format %q (supported: png, jpeg, gif)", ErrUnsupportedQRLogo, format)
+	}
+	if config.Width < options.MinDimension || config.Height < options.MinDimension {
+		return "", fmt.Errorf("qr code logo is %dx%d, smaller than the minimum of %d pixels", config.Width, config.Height, options.MinDimension)
+	}
+
+	tooLarge := config.Width > options.MaxDimension || config.Height > options.MaxDimension
+	if tooLarge && !options.Resize {
+		return "", fmt.Errorf("qr code logo is %dx%d, larger than the maximum of %d pixels", config.Width, config.Height, options.MaxDimension)
+	}
+	if config.Width*config.Height > maxQRLogoSourcePixels {
+		return "", fmt.Errorf("qr code logo is %dx%d, too large to decode", config.Width, config.Height)
+	}
+	// Decode the whole image, not just its header, so truncated files are caught here.
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnsupportedQRLogo, err)
+	}
+	if tooLarge {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, scaleToFit(src, options.MaxDimension)); err != nil {
+			return "", err
+		}
+		data, contentType = buf.Bytes(), "image/png"
+	}
+
+	if int64(len(data)) > options.MaxBytes {
+		return "", fmt.Errorf("qr code logo is larger than %d bytes", options.MaxBytes)
+	}
+	return "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// UploadQRLogo encodes the image read from r with EncodeQRLogo and sets it as
+// the logo of shortURL's QR code.
+func (c *Client) UploadQRLogo(shortURL string, r io.Reader, options QRLogoOptions) (*QRCode, error) {
+	logo, err := EncodeQRLogo(r, options)
+	if err != nil {
+		return nil, err
+	}
+	return c.UpdateQRCode(QRCodeUpdateRequest{ShortURL: shortURL, Image: &logo})
+}
+
+// UploadQRLogoFile is UploadQRLogo for an image file on disk.
+func (c *Client) UploadQRLogoFile(shortURL, path string, options QRLogoOptions) (*QRCode, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	qrCode, err := c.UploadQRLogo(shortURL, f, options)
+	if err != nil && errors.Is(err, ErrUnsupportedQRLogo) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return qrCode, err
+}
+
+// scaleToFit shrinks src so its larger side is limit pixels, averaging the
+// source pixels that fall into each destination pixel.
+func scaleToFit(src image.Image, limit int) *image.RGBA {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	dstWidth, dstHeight := limit, limit
+	if width > height {
+		dstHeight = height * limit / width
+	} else {
+		dstWidth = width * limit / height
+	}
+	if dstWidth < 1 {
+		dstWidth = 1
+	}
+	if dstHeight < 1 {
+		dstHeight = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		y0 := bounds.Min.Y + y*height/dstHeight
+		y1 := bounds.Min.Y + (y+1)*height/dstHeight
+		for x := 0; x < dstWidth; x++ {
+			x0 := bounds.Min.X + x*width/dstWidth
+			x1 := bounds.Min.X + (x+1)*width/dstWidth
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i+0] = uint8(r / n >> 8)
+			dst.Pix[i+1] = uint8(g / n >> 8)
+			dst.Pix[i+2] = uint8(b / n >> 8)
+			dst.Pix[i+3] = uint8(a / n >> 8)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,212 @@
+package tly
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+)
+
+// =====================
+// QR Code Rendering
+// =====================
+
+// Defaults applied by QRRenderOptions.
+const (
+	DefaultQRImageSize = 512
+	DefaultQRQuietZone = 4
+)
+
+// QRRenderOptions controls GenerateQRImage.
+type QRRenderOptions struct {
+	// Level is the error correction level. Defaults to QRLevelM.
+	Level QRLevel
+	// Size is the width and height of the image in pixels. Defaults to
+	// DefaultQRImageSize. PNG output is never smaller than one pixel per module.
+	Size int
+	// QuietZone is the light border in modules. Zero means
+	// DefaultQRQuietZone; use a negative value for no border.
+	QuietZone int
+	// Style supplies the colors, as configured in T.LY: DotsColor for the
+	// modules, CornerDotsColor for the centre of the three corner patterns and
+	// BackgroundColor for the rest. The "dots" and rounded dot styles are
+	// approximated; other options are ignored.
+	Style QRCodeOptions
+}
+
+type qrPalette struct {
+	background, dots, corners color.NRGBA
+}
+
+func (o QRRenderOptions) normalized() (QRRenderOptions, qrPalette, error) {
+	if o.Size <= 0 {
+		o.Size = DefaultQRImageSize
+	}
+	if o.QuietZone == 0 {
+		o.QuietZone = DefaultQRQuietZone
+	} else if o.QuietZone < 0 {
+		o.QuietZone = 0
+	}
+	if err := o.Style.Validate(); err != nil {
+		return o, qrPalette{}, err
+	}
+
+	palette := qrPalette{
+		background: color.NRGBA{0xFF, 0xFF, 0xFF, 0xFF},
+		dots:       color.NRGBA{0x00, 0x00, 0x00, 0xFF},
+	}
+	if o.Style.BackgroundColor != "" {
+		palette.background = parseHexColor(o.Style.BackgroundColor)
+	}
+	if o.Style.DotsColor != "" {
+		palette.dots = parseHexColor(o.Style.DotsColor)
+	}
+	palette.corners = palette.dots
+	if o.Style.CornerDotsColor != "" {
+		palette.corners = parseHexColor(o.Style.CornerDotsColor)
+	}
+	return o, palette, nil
+}
+
+// parseHexColor parses a color already checked by ValidateHexColor.
+func parseHexColor(hex string) color.NRGBA {
+	digits := hex[1:]
+	if len(digits) == 3 {
+		digits = string([]byte{digits[0], digits[0], digits[1], digits[1], digits[2], digits[2]})
+	}
+	value, _ := strconv.ParseUint(digits, 16, 32)
+	return color.NRGBA{uint8(value >> 16), uint8(value >> 8), uint8(value), 0xFF}
+}
+
+func qrRoundDots(style QRDotsStyle) bool {
+	return style == QRDotsDots
+}
+
+func qrRoundedDots(style QRDotsStyle) bool {
+	return style == QRDotsRounded || style == QRDotsExtraRounded || style == QRDotsClassyRounded
+}
+
+// GenerateQRImage encodes content, typically a ShortLink.ShortURL, as a QR
+// code without calling the API. format is QRFormatPNG or QRFormatSVG.
+func GenerateQRImage(content, format string, options QRRenderOptions) (*QRImage, error) {
+	matrix, err := EncodeQR(content, options.Level)
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	switch format {
+	case QRFormatPNG, "":
+		format = QRFormatPNG
+		data, err = matrix.PNG(options)
+	case QRFormatSVG:
+		data, err = matrix.SVG(options)
+	default:
+		return nil, fmt.Errorf("unsupported qr code format %q (supported: png, svg)", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newQRImage(format, data), nil
+}
+
+// PNG renders the matrix as a PNG image.
+func (m *QRMatrix) PNG(options QRRenderOptions) ([]byte, error) {
+	options, palette, err := options.normalized()
+	if err != nil {
+		return nil, err
+	}
+	modules := m.size + 2*options.QuietZone
+	scale := options.Size / modules
+	if scale < 1 {
+		scale = 1
+	}
+	size := options.Size
+	if size < modules*scale {
+		size = modules * scale
+	}
+	offset := (size-modules*scale)/2 + options.QuietZone*scale
+
+	const (
+		background = iota
+		dots
+		corners
+	)
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{palette.background, palette.dots, palette.corners})
+	round := qrRoundDots(options.Style.DotsStyle) && scale >= 3
+	for y := 0; y < m.size; y++ {
+		for x := 0; x < m.size; x++ {
+			if !m.Dark(x, y) {
+				continue
+			}
+			index := uint8(dots)
+			finder, centre := m.isFinder(x, y)
+			if centre {
+				index = corners
+			}
+			for py := 0; py < scale; py++ {
+				for px := 0; px < scale; px++ {
+					if round && !finder {
+						// Keep pixels whose centre falls inside the module's circle.
+						dx, dy := 2*px+1-scale, 2*py+1-scale
+						if dx*dx+dy*dy > scale*scale {
+							continue
+						}
+					}
+					img.SetColorIndex(offset+x*scale+px, offset+y*scale+py, index)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG renders the matrix as an SVG document scaled to options.Size.
+func (m *QRMatrix) SVG(options QRRenderOptions) ([]byte, error) {
+	options, palette, err := options.normalized()
+	if err != nil {
+		return nil, err
+	}
+	modules := m.size + 2*options.QuietZone
+	hex := func(c color.NRGBA) string {
+		return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+	}
+
+	var dots, corners bytes.Buffer
+	round := qrRoundDots(options.Style.DotsStyle)
+	rounded := qrRoundedDots(options.Style.DotsStyle)
+	for y := 0; y < m.size; y++ {
+		for x := 0; x < m.size; x++ {
+			if !m.Dark(x, y) {
+				continue
+			}
+			px, py := x+options.QuietZone, y+options.QuietZone
+			finder, centre := m.isFinder(x, y)
+			switch {
+			case centre:
+				fmt.Fprintf(&corners, "M%d %dh1v1h-1z", px, py)
+			case finder || (!round && !rounded):
+				fmt.Fprintf(&dots, "M%d %dh1v1h-1z", px, py)
+			case round:
+				fmt.Fprintf(&dots, "M%d %d.5a.5 .5 0 1 0 1 0a.5 .5 0 1 0-1 0z", px, py)
+			default:
+				fmt.Fprintf(&dots, "M%d.3 %dh.4a.3 .3 0 0 1 .3 .3v.4a.3 .3 0 0 1-.3 .3h-.4a.3 .3 0 0 1-.3-.3v-.4a.3 .3 0 0 1 .3-.3z", px, py)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", options.Size, options.Size, modules, modules)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="%s"/>`+"\n", modules, modules, hex(palette.background))
+	fmt.Fprintf(&buf, `<path fill="%s" d="%s"/>`+"\n", hex(palette.dots), dots.String())
+	fmt.Fprintf(&buf, `<path fill="%s" d="%s"/>`+"\n", hex(palette.corners), corners.String())
+	buf.WriteString("</svg>\n")
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,182 @@
+package tly
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// =====================
+// QR Code Styles
+// =====================
+
+// QRDotsStyle is the shape used for the data modules of a QR code.
+type QRDotsStyle string
+
+// Dot styles supported by the API.
+const (
+	QRDotsSquare        QRDotsStyle = "square"
+	QRDotsDots          QRDotsStyle = "dots"
+	QRDotsRounded       QRDotsStyle = "rounded"
+	QRDotsClassy        QRDotsStyle = "classy"
+	QRDotsClassyRounded QRDotsStyle = "classy-rounded"
+	QRDotsExtraRounded  QRDotsStyle = "extra-rounded"
+)
+
+// QRCornerStyle is the shape used for the three finder patterns of a QR code.
+type QRCornerStyle string
+
+// Corner styles supported by the API.
+const (
+	QRCornerSquare       QRCornerStyle = "square"
+	QRCornerDot          QRCornerStyle = "dot"
+	QRCornerExtraRounded QRCornerStyle = "extra-rounded"
+)
+
+var (
+	validDotsStyles = map[QRDotsStyle]bool{
+		QRDotsSquare:        true,
+		QRDotsDots:          true,
+		QRDotsRounded:       true,
+		QRDotsClassy:        true,
+		QRDotsClassyRounded: true,
+		QRDotsExtraRounded:  true,
+	}
+	validCornerStyles = map[QRCornerStyle]bool{
+		QRCornerSquare:       true,
+		QRCornerDot:          true,
+		QRCornerExtraRounded: true,
+	}
+	hexColorPattern = regexp.MustCompile(`^#([0-9A-Fa-f]{3}|[0-9A-Fa-f]{6})$`)
+)
+
+// Valid reports whether s is a dot style supported by the API.
+func (s QRDotsStyle) Valid() bool {
+	return validDotsStyles[s]
+}
+
+// Valid reports whether s is a corner style supported by the API.
+func (s QRCornerStyle) Valid() bool {
+	return validCornerStyles[s]
+}
+
+// ValidateHexColor checks that color is a CSS hex color such as "#000" or "#1a2b3c".
+func ValidateHexColor(color string) error {
+	if !hexColorPattern.MatchString(color) {
+		return fmt.Errorf("invalid hex color %q (expected #rgb or #rrggbb)", color)
+	}
+	return nil
+}
+
+// QRCodeOptions is the styling stored for a link's QR code. Options read from
+// one link can be applied to another with UpdateRequest.
+type QRCodeOptions struct {
+	Image           string        `json:"image,omitempty"`
+	BackgroundColor string        `json:"background_color,omitempty"`
+	CornerDotsColor string        `json:"corner_dots_color,omitempty"`
+	DotsColor       string        `json:"dots_color,omitempty"`
+	DotsStyle       QRDotsStyle   `json:"dots_style,omitempty"`
+	CornerStyle     QRCornerStyle `json:"corner_style,omitempty"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Besides an object it accepts
+// null, an empty array (returned when no options are set) and an object
+// encoded as a JSON string.
+func (o *QRCodeOptions) UnmarshalJSON(data []byte) error {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '"' {
+		var encoded string
+		if err := json.Unmarshal(trimmed, &encoded); err != nil {
+			return err
+		}
+		trimmed = bytes.TrimSpace([]byte(encoded))
+	}
+	switch string(trimmed) {
+	case "", "null", "[]", "{}":
+		*o = QRCodeOptions{}
+		return nil
+	}
+
+	type plain QRCodeOptions
+	var decoded plain
+	if err := json.Unmarshal(trimmed, &decoded); err != nil {
+		return fmt.Errorf("unable to decode qr code options: %v", err)
+	}
+	*o = QRCodeOptions(decoded)
+	return nil
+}
+
+// IsZero reports whether no option is set.
+func (o QRCodeOptions) IsZero() bool {
+	return o == QRCodeOptions{}
+}
+
+// Validate checks colors and styles against the values the API accepts.
+func (o QRCodeOptions) Validate() error {
+	verr := &ValidationError{}
+	validateQRStyle(verr, &o.BackgroundColor, &o.CornerDotsColor, &o.DotsColor, &o.DotsStyle, &o.CornerStyle)
+	return verr.err()
+}
+
+// UpdateRequest returns a request that applies these options to shortURL.
+// Empty options are left out of the request.
+func (o QRCodeOptions) UpdateRequest(shortURL string) QRCodeUpdateRequest {
+	optional := func(s string) *string {
+		if s == "" {
+			return nil
+		}
+		return &s
+	}
+	req := QRCodeUpdateRequest{
+		ShortURL:        shortURL,
+		Image:           optional(o.Image),
+		BackgroundColor: optional(o.BackgroundColor),
+		CornerDotsColor: optional(o.CornerDotsColor),
+		DotsColor:       optional(o.DotsColor),
+	}
+	if o.DotsStyle != "" {
+		style := o.DotsStyle
+		req.DotsStyle = &style
+	}
+	if o.CornerStyle != "" {
+		style := o.CornerStyle
+		req.CornerStyle = &style
+	}
+	return req
+}
+
+// Validate checks the short URL, colors and styles.
+// UpdateQRCode calls it before sending unless Client.SkipValidation is set.
+func (r QRCodeUpdateRequest) Validate() error {
+	verr := &ValidationError{}
+	if strings.TrimSpace(r.ShortURL) == "" {
+		verr.Add("short_url", "The short url field is required.")
+	} else if err := validateHTTPURL(r.ShortURL); err != nil {
+		verr.Add("short_url", err.Error())
+	}
+	validateQRStyle(verr, r.BackgroundColor, r.CornerDotsColor, r.DotsColor, r.DotsStyle, r.CornerStyle)
+	return verr.err()
+}
+
+func validateQRStyle(verr *ValidationError, background, cornerDots, dots *string, dotsStyle *QRDotsStyle, cornerStyle *QRCornerStyle) {
+	for field, color := range map[string]*string{
+		"background_color":  background,
+		"corner_dots_color": cornerDots,
+		"dots_color":        dots,
+	} {
+		if color == nil || *color == "" {
+			continue
+		}
+		if err := ValidateHexColor(*color); err != nil {
+			verr.Add(field, err.Error())
+		}
+	}
+	if dotsStyle != nil && *dotsStyle != "" && !dotsStyle.Valid() {
+		verr.Add("dots_style", fmt.Sprintf("unsupported dots style %q", *dotsStyle))
+	}
+	if cornerStyle != nil && *cornerStyle != "" && !cornerStyle.Valid() {
+		verr.Add("corner_style", fmt.Sprintf("unsupported corner style %q", *cornerStyle))
+	}
+}
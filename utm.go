@@ -0,0 +1,240 @@
+package tly
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// =====================
+// UTM Parameters
+// =====================
+
+// UTM query parameter names.
+const (
+	UTMSource   = "utm_source"
+	UTMMedium   = "utm_medium"
+	UTMCampaign = "utm_campaign"
+	UTMContent  = "utm_content"
+	UTMTerm     = "utm_term"
+)
+
+// Params returns the preset's non-empty values keyed by UTM parameter name.
+func (p UTMPreset) Params() map[string]string {
+	return utmParams(p.Source, p.Medium, p.Campaign, p.Content, p.Term)
+}
+
+// Params returns the request's non-empty values keyed by UTM parameter name.
+func (r UTMPresetRequest) Params() map[string]string {
+	return utmParams(r.Source, r.Medium, r.Campaign, r.Content, r.Term)
+}
+
+func utmParams(source, medium, campaign, content, term string) map[string]string {
+	params := map[string]string{}
+	for key, value := range map[string]string{
+		UTMSource:   source,
+		UTMMedium:   medium,
+		UTMCampaign: campaign,
+		UTMContent:  content,
+		UTMTerm:     term,
+	} {
+		if value != "" {
+			params[key] = value
+		}
+	}
+	return params
+}
+
+// utmOrder is the order UTM parameters are appended in. Other parameters
+// follow in alphabetical order.
+var utmOrder = []string{UTMSource, UTMMedium, UTMCampaign, UTMContent, UTMTerm}
+
+func utmParamOrder(params map[string]string) []string {
+	keys := make([]string, 0, len(params))
+	known := map[string]bool{}
+	for _, key := range utmOrder {
+		known[key] = true
+		if _, ok := params[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+	var extra []string
+	for key := range params {
+		if !known[key] {
+			extra = append(extra, key)
+		}
+	}
+	sort.Strings(extra)
+	return append(keys, extra...)
+}
+
+// ApplyUTMPreset adds the preset's UTM parameters to longURL. Other query
+// parameters and the fragment are kept. A utm_* parameter already present in
+// the URL, matched without regard to case, is left alone unless overwrite is
+// true, in which case its value is replaced in the same position and any
+// repeats of it are removed. Missing parameters are appended.
+func ApplyUTMPreset(longURL string, preset UTMPreset, overwrite bool) (string, error) {
+	return applyUTMParams(longURL, preset.Params(), overwrite)
+}
+
+// applyUTMParams edits the raw query directly instead of re-encoding it, so
+// parameter order and encoding the caller chose are preserved.
+func applyUTMParams(longURL string, params map[string]string, overwrite bool) (string, error) {
+	if err := validateHTTPURL(longURL); err != nil {
+		return "", err
+	}
+	parsed, err := url.Parse(longURL)
+	if err != nil {
+		return "", err
+	}
+
+	// Keys are matched without regard to case, as ParseUTMParams does.
+	byLower := make(map[string]string, len(params))
+	for key := range params {
+		byLower[strings.ToLower(key)] = key
+	}
+	present := map[string]bool{}
+	var kept []string
+	if parsed.RawQuery != "" {
+		for _, pair := range strings.Split(parsed.RawQuery, "&") {
+			if pair == "" {
+				continue
+			}
+			rawKey := pair
+			if i := strings.IndexByte(pair, '='); i >= 0 {
+				rawKey = pair[:i]
+			}
+			key, err := url.QueryUnescape(rawKey)
+			if err != nil {
+				key = rawKey
+			}
+			name, ours := byLower[strings.ToLower(key)]
+			if !ours {
+				kept = append(kept, pair)
+				continue
+			}
+			if overwrite {
+				// Replace the first occurrence where it sits and drop repeats.
+				if present[name] {
+					continue
+				}
+				pair = name + "=" + url.QueryEscape(params[name])
+			}
+			present[name] = true
+			kept = append(kept, pair)
+		}
+	}
+	for _, key := range utmParamOrder(params) {
+		if present[key] {
+			continue
+		}
+		kept = append(kept, key+"="+url.QueryEscape(params[key]))
+	}
+
+	parsed.RawQuery = strings.Join(kept, "&")
+	parsed.ForceQuery = false
+	return parsed.String(), nil
+}
+
+// FindUTMPresetByName returns the preset with the given name, matched without
+// regard to case.
+func (c *Client) FindUTMPresetByName(name string) (*UTMPreset, error) {
+	presets, err := c.ListUTMPresets()
+	if err != nil {
+		return nil, err
+	}
+	for _, preset := range presets {
+		if strings.EqualFold(strings.TrimSpace(preset.Name), strings.TrimSpace(name)) {
+			found := preset
+			return &found, nil
+		}
+	}
+	return nil, fmt.Errorf("utm preset %q not found", name)
+}
+
+// ApplyUTMPresetByID fetches the preset with GetUTMPreset and applies it to longURL.
+func (c *Client) ApplyUTMPresetByID(longURL string, id int, overwrite bool) (string, error) {
+	preset, err := c.GetUTMPreset(id)
+	if err != nil {
+		return "", err
+	}
+	return ApplyUTMPreset(longURL, *preset, overwrite)
+}
+
+// ApplyUTMPresetByName looks the preset up by name and applies it to longURL.
+func (c *Client) ApplyUTMPresetByName(longURL, name string, overwrite bool) (string, error) {
+	preset, err := c.FindUTMPresetByName(name)
+	if err != nil {
+		return "", err
+	}
+	return ApplyUTMPreset(longURL, *preset, overwrite)
+}
+
+// UTMPresetRef selects the preset used by CreateShortLinkWithUTM. Set exactly
+// one of ID, Name or Preset.
+type UTMPresetRef struct {
+	ID     int
+	Name   string
+	Preset *UTMPresetRequest
+}
+
+func (c *Client) resolveUTMPreset(ref UTMPresetRef) (*UTMPreset, error) {
+	set := 0
+	for _, ok := range []bool{ref.ID != 0, ref.Name != "", ref.Preset != nil} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, fmt.Errorf("utm preset reference must set exactly one of ID, Name or Preset")
+	}
+
+	switch {
+	case ref.ID != 0:
+		return c.GetUTMPreset(ref.ID)
+	case ref.Name != "":
+		return c.FindUTMPresetByName(ref.Name)
+	default:
+		return &UTMPreset{
+			Name:     ref.Preset.Name,
+			Source:   ref.Preset.Source,
+			Medium:   ref.Preset.Medium,
+			Campaign: ref.Preset.Campaign,
+			Content:  ref.Preset.Content,
+			Term:     ref.Preset.Term,
+		}, nil
+	}
+}
+
+// CreateShortLinkWithUTM applies a UTM preset to reqData.LongURL and creates
+// the link. The preset is recorded under "utm_preset" in the link's meta,
+// alongside any meta already set on the request.
+func (c *Client) CreateShortLinkWithUTM(reqData ShortLinkCreateRequest, ref UTMPresetRef, overwrite bool) (*ShortLink, error) {
+	preset, err := c.resolveUTMPreset(ref)
+	if err != nil {
+		return nil, err
+	}
+	longURL, err := ApplyUTMPreset(reqData.LongURL, *preset, overwrite)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := DecodeLinkMeta(reqData.Meta)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := *existing
+	meta.UTMPreset = &MetaUTMPreset{
+		ID:       preset.ID,
+		Name:     preset.Name,
+		Source:   preset.Source,
+		Medium:   preset.Medium,
+		Campaign: preset.Campaign,
+		Content:  preset.Content,
+		Term:     preset.Term,
+	}
+	reqData.LongURL = longURL
+	reqData.Meta = &meta
+	return c.CreateShortLink(reqData)
+}
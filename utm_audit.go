@@ -0,0 +1,206 @@
+package tly
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// =====================
+// UTM Audit
+// =====================
+
+// Problems reported in UTMViolation.Problem.
+const (
+	UTMProblemMissing = "missing"
+	UTMProblemNaming  = "naming"
+	UTMProblemPreset  = "preset"
+)
+
+// DefaultUTMValuePattern allows lower-case letters, digits, dots, dashes and underscores.
+var DefaultUTMValuePattern = regexp.MustCompile(`^[a-z0-9._-]+$`)
+
+var utmInvalidRun = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// UTMAuditRules are the conventions AuditUTM checks links against.
+type UTMAuditRules struct {
+	// RequiredParams must be present on every link. Defaults to utm_source,
+	// utm_medium and utm_campaign.
+	RequiredParams []string
+	// ValuePattern must match every utm_* value. Defaults to DefaultUTMValuePattern.
+	ValuePattern *regexp.Regexp
+	// AllowedPresets, when set, requires each link's source, medium and
+	// campaign to match one of these presets.
+	AllowedPresets []UTMPreset
+}
+
+// UTMAuditOptions controls AuditUTM.
+type UTMAuditOptions struct {
+	// Filter is passed to the list endpoint.
+	Filter ListShortLinksOptions
+	Rules  UTMAuditRules
+	// Fix rewrites offending long URLs through BulkUpdateLinks: values that
+	// break the naming rule are normalized, and missing parameters are filled
+	// from DefaultPreset when it is set. Preset mismatches are only reported.
+	Fix           bool
+	DefaultPreset *UTMPreset
+	// BatchSize is the number of links per BulkUpdateLinks call.
+	// Defaults to DefaultBulkChunkSize.
+	BatchSize int
+}
+
+// UTMViolation is one rule broken by one link.
+type UTMViolation struct {
+	Link    ShortLink
+	Param   string
+	Value   string
+	Problem string
+}
+
+// UTMFix is a long URL rewritten by AuditUTM.
+type UTMFix struct {
+	Link       ShortLink
+	NewLongURL string
+	Err        error
+}
+
+// UTMAuditReport lists the violations found and any fixes applied.
+type UTMAuditReport struct {
+	Checked    int
+	Violations []UTMViolation
+	Fixes      []UTMFix
+}
+
+// ParseUTMParams returns the utm_* query parameters of longURL.
+func ParseUTMParams(longURL string) (map[string]string, error) {
+	parsed, err := url.Parse(longURL)
+	if err != nil {
+		return nil, err
+	}
+	params := map[string]string{}
+	for key, values := range parsed.Query() {
+		if strings.HasPrefix(strings.ToLower(key), "utm_") && len(values) > 0 {
+			params[strings.ToLower(key)] = values[0]
+		}
+	}
+	return params, nil
+}
+
+// NormalizeUTMValue lower-cases value and replaces runs of characters outside
+// DefaultUTMValuePattern with an underscore.
+func NormalizeUTMValue(value string) string {
+	value = utmInvalidRun.ReplaceAllString(strings.ToLower(strings.TrimSpace(value)), "_")
+	return strings.Trim(value, "_")
+}
+
+// AuditUTM walks every link matching options.Filter and checks the UTM
+// parameters of its long URL against options.Rules.
+func (c *Client) AuditUTM(ctx context.Context, options UTMAuditOptions) (*UTMAuditReport, error) {
+	rules := options.Rules
+	if len(rules.RequiredParams) == 0 {
+		rules.RequiredParams = []string{UTMSource, UTMMedium, UTMCampaign}
+	}
+	if rules.ValuePattern == nil {
+		rules.ValuePattern = DefaultUTMValuePattern
+	}
+
+	bound := c.WithContext(ctx)
+	report := &UTMAuditReport{}
+	var fixes []UTMFix
+	err := bound.EachShortLink(options.Filter, func(link ShortLink) error {
+		report.Checked++
+		violations, fixed := auditLinkUTM(link, rules, options.DefaultPreset)
+		report.Violations = append(report.Violations, violations...)
+		if options.Fix && fixed != "" && fixed != link.LongURL {
+			fixes = append(fixes, UTMFix{Link: link, NewLongURL: fixed})
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+	if len(fixes) == 0 {
+		return report, nil
+	}
+
+	batchSize := options.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBulkChunkSize
+	}
+	for start := 0; start < len(fixes); start += batchSize {
+		end := start + batchSize
+		if end > len(fixes) {
+			end = len(fixes)
+		}
+		entries := make([]BulkUpdateLink, 0, end-start)
+		for _, fix := range fixes[start:end] {
+			entries = append(entries, BulkUpdateLink{ShortURL: fix.Link.ShortURL, LongURL: fix.NewLongURL})
+		}
+		_, err := bound.BulkUpdateLinks(BulkUpdateRequest{Links: entries})
+		for i := start; i < end; i++ {
+			fixes[i].Err = err
+		}
+	}
+	report.Fixes = fixes
+	return report, ctx.Err()
+}
+
+// auditLinkUTM returns the link's violations and, when they can be fixed, the
+// corrected long URL.
+func auditLinkUTM(link ShortLink, rules UTMAuditRules, defaultPreset *UTMPreset) ([]UTMViolation, string) {
+	params, err := ParseUTMParams(link.LongURL)
+	if err != nil {
+		return []UTMViolation{{Link: link, Param: "long_url", Value: link.LongURL, Problem: UTMProblemNaming}}, ""
+	}
+
+	var violations []UTMViolation
+	replacements := map[string]string{}
+	for _, param := range rules.RequiredParams {
+		if params[param] == "" {
+			violations = append(violations, UTMViolation{Link: link, Param: param, Problem: UTMProblemMissing})
+		}
+	}
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if value := params[key]; !rules.ValuePattern.MatchString(value) {
+			violations = append(violations, UTMViolation{Link: link, Param: key, Value: value, Problem: UTMProblemNaming})
+			if normalized := NormalizeUTMValue(value); normalized != "" {
+				replacements[key] = normalized
+			}
+		}
+	}
+	if len(rules.AllowedPresets) > 0 && !matchesAnyPreset(params, rules.AllowedPresets) {
+		violations = append(violations, UTMViolation{Link: link, Param: UTMCampaign, Value: params[UTMCampaign], Problem: UTMProblemPreset})
+	}
+	if len(violations) == 0 {
+		return nil, ""
+	}
+
+	fixed := link.LongURL
+	if len(replacements) > 0 {
+		if fixed, err = applyUTMParams(fixed, replacements, true); err != nil {
+			return violations, ""
+		}
+	}
+	if defaultPreset != nil {
+		if fixed, err = ApplyUTMPreset(fixed, *defaultPreset, false); err != nil {
+			return violations, ""
+		}
+	}
+	return violations, fixed
+}
+
+func matchesAnyPreset(params map[string]string, presets []UTMPreset) bool {
+	for _, preset := range presets {
+		if params[UTMSource] == preset.Source && params[UTMMedium] == preset.Medium && params[UTMCampaign] == preset.Campaign {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package tly
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// =====================
+// UTM Preset Sync
+// =====================
+
+// Actions in a UTM preset sync plan.
+const (
+	SyncCreate    = "create"
+	SyncUpdate    = "update"
+	SyncDelete    = "delete"
+	SyncUnchanged = "unchanged"
+)
+
+// UTMPresetChange is one step of a UTM preset sync plan.
+type UTMPresetChange struct {
+	Action  string
+	Name    string
+	Current *UTMPreset
+	Desired *UTMPresetRequest
+	Err     error
+}
+
+// UTMPresetPlan is the set of changes that makes the account's presets match
+// a desired list.
+type UTMPresetPlan struct {
+	Changes []UTMPresetChange
+}
+
+// HasChanges reports whether applying the plan would change anything.
+func (p *UTMPresetPlan) HasChanges() bool {
+	for _, change := range p.Changes {
+		if change.Action != SyncUnchanged {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadUTMPresets reads a desired preset list from JSON: an array of objects
+// with the same fields as UTMPresetRequest. YAML sources can be converted to
+// JSON before loading.
+func LoadUTMPresets(r io.Reader) ([]UTMPresetRequest, error) {
+	var presets []UTMPresetRequest
+	if err := json.NewDecoder(r).Decode(&presets); err != nil {
+		return nil, fmt.Errorf("unable to decode utm presets: %v", err)
+	}
+	return presets, nil
+}
+
+// PlanUTMPresetSync compares desired with ListUTMPresets by name (ignoring
+// case) and returns the changes needed to match. Presets missing from desired
+// are deleted only when prune is true.
+func (c *Client) PlanUTMPresetSync(desired []UTMPresetRequest, prune bool) (*UTMPresetPlan, error) {
+	wanted := map[string]bool{}
+	for _, preset := range desired {
+		key := strings.ToLower(strings.TrimSpace(preset.Name))
+		if key == "" {
+			return nil, fmt.Errorf("desired utm preset has no name")
+		}
+		if wanted[key] {
+			return nil, fmt.Errorf("duplicate desired utm preset %q", preset.Name)
+		}
+		wanted[key] = true
+	}
+
+	current, err := c.ListUTMPresets()
+	if err != nil {
+		return nil, err
+	}
+	existing := map[string]UTMPreset{}
+	for _, preset := range current {
+		existing[strings.ToLower(strings.TrimSpace(preset.Name))] = preset
+	}
+
+	plan := &UTMPresetPlan{}
+	for i := range desired {
+		want := desired[i]
+		have, ok := existing[strings.ToLower(strings.TrimSpace(want.Name))]
+		change := UTMPresetChange{Name: want.Name, Desired: &want}
+		switch {
+		case !ok:
+			change.Action = SyncCreate
+		case have.Name == want.Name && have.Source == want.Source && have.Medium == want.Medium &&
+			have.Campaign == want.Campaign && have.Content == want.Content && have.Term == want.Term:
+			change.Action = SyncUnchanged
+			change.Current = &have
+		default:
+			change.Action = SyncUpdate
+			change.Current = &have
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+	if prune {
+		for i := range current {
+			have := current[i]
+			if !wanted[strings.ToLower(strings.TrimSpace(have.Name))] {
+				plan.Changes = append(plan.Changes, UTMPresetChange{Action: SyncDelete, Name: have.Name, Current: &have})
+			}
+		}
+	}
+	return plan, nil
+}
+
+// ApplyUTMPresetPlan carries out the plan's changes. Each change records its
+// own error; the first one is also returned.
+func (c *Client) ApplyUTMPresetPlan(plan *UTMPresetPlan) error {
+	var firstErr error
+	for i := range plan.Changes {
+		change := &plan.Changes[i]
+		switch change.Action {
+		case SyncCreate:
+			_, change.Err = c.CreateUTMPreset(*change.Desired)
+		case SyncUpdate:
+			_, change.Err = c.UpdateUTMPreset(change.Current.ID, *change.Desired)
+		case SyncDelete:
+			change.Err = c.DeleteUTMPreset(change.Current.ID)
+		}
+		if change.Err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("%s utm preset %q: %v", change.Action, change.Name, change.Err)
+		}
+	}
+	return firstErr
+}
+
+// SyncUTMPresets plans and applies the changes that make the account's
+// presets match desired, returning the plan with per-change errors.
+func (c *Client) SyncUTMPresets(desired []UTMPresetRequest, prune bool) (*UTMPresetPlan, error) {
+	plan, err := c.PlanUTMPresetSync(desired, prune)
+	if err != nil {
+		return nil, err
+	}
+	return plan, c.ApplyUTMPresetPlan(plan)
+}
@@ -0,0 +1,55 @@
+package tly
+
+import "testing"
+
+func TestApplyUTMPresetMixedCaseKeys(t *testing.T) {
+	preset := UTMPreset{Source: "newsletter", Medium: "email"}
+	tests := []struct {
+		name      string
+		longURL   string
+		overwrite bool
+		want      string
+	}{
+		{
+			name:      "overwrite replaces in place",
+			longURL:   "https://example.com/page?UTM_Source=News%20Letter&x=1#top",
+			overwrite: true,
+			want:      "https://example.com/page?utm_source=newsletter&x=1&utm_medium=email#top",
+		},
+		{
+			name:      "overwrite drops repeats",
+			longURL:   "https://example.com/?utm_source=a&Utm_Source=b",
+			overwrite: true,
+			want:      "https://example.com/?utm_source=newsletter&utm_medium=email",
+		},
+		{
+			name:    "existing value kept without overwrite",
+			longURL: "https://example.com/?UTM_Source=old",
+			want:    "https://example.com/?UTM_Source=old&utm_medium=email",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyUTMPreset(tt.longURL, preset, tt.overwrite)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditLinkUTMFixesMixedCaseKeys(t *testing.T) {
+	link := ShortLink{LongURL: "https://example.com/?UTM_Source=News%20Letter&utm_medium=email&utm_campaign=spring"}
+	rules := UTMAuditRules{RequiredParams: []string{UTMSource}, ValuePattern: DefaultUTMValuePattern}
+	violations, fixed := auditLinkUTM(link, rules, nil)
+	if len(violations) != 1 || violations[0].Param != UTMSource {
+		t.Fatalf("violations = %+v", violations)
+	}
+	want := "https://example.com/?utm_source=news_letter&utm_medium=email&utm_campaign=spring"
+	if fixed != want {
+		t.Errorf("fixed = %s, want %s", fixed, want)
+	}
+}
@@ -0,0 +1,194 @@
+package tly
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// =====================
+// Request Validation
+// =====================
+
+// Limits applied by client-side validation.
+const (
+	maxShortIDLength     = 100
+	maxPasswordLength    = 255
+	maxDescriptionLength = 255
+)
+
+var (
+	shortIDPattern  = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	hostnamePattern = regexp.MustCompile(`^([A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?\.)+[A-Za-z]{2,63}$`)
+)
+
+// ValidationError is returned when a request fails client-side validation.
+// It mirrors the shape of the API's 422 response: a message plus a list of
+// problems per field.
+type ValidationError struct {
+	Message string              `json:"message"`
+	Errors  map[string][]string `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Errors))
+	for field := range e.Errors {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	parts := make([]string, 0, len(fields))
+	for _, field := range fields {
+		parts = append(parts, fmt.Sprintf("%s: %s", field, strings.Join(e.Errors[field], ", ")))
+	}
+	return fmt.Sprintf("%s (%s)", e.Message, strings.Join(parts, "; "))
+}
+
+// Add records a problem with field.
+func (e *ValidationError) Add(field, message string) {
+	if e.Errors == nil {
+		e.Errors = map[string][]string{}
+	}
+	e.Errors[field] = append(e.Errors[field], message)
+}
+
+// err returns e when it holds at least one problem, and nil otherwise.
+func (e *ValidationError) err() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	if e.Message == "" {
+		e.Message = "The given data was invalid."
+	}
+	return e
+}
+
+// Validate checks the request for problems the API would reject.
+// CreateShortLink calls it before sending unless Client.SkipValidation is set.
+func (r ShortLinkCreateRequest) Validate() error {
+	verr := &ValidationError{}
+	validateLongURL(verr, r.LongURL)
+	if r.ShortID != nil {
+		validateShortID(verr, *r.ShortID)
+	}
+	if r.Domain != "" {
+		validateDomain(verr, r.Domain)
+	}
+	validateLinkOptions(verr, r.ExpireAtDatetime, r.ExpireAtViews, r.Description, r.Password, r.Tags, r.Pixels, r.Meta)
+	return verr.err()
+}
+
+// Validate checks the request for problems the API would reject.
+// UpdateShortLink calls it before sending unless Client.SkipValidation is set.
+func (r ShortLinkUpdateRequest) Validate() error {
+	verr := &ValidationError{}
+	if strings.TrimSpace(r.ShortURL) == "" {
+		verr.Add("short_url", "The short url field is required.")
+	} else if err := validateHTTPURL(r.ShortURL); err != nil {
+		verr.Add("short_url", err.Error())
+	}
+	validateLongURL(verr, r.LongURL)
+	if r.ShortID != nil {
+		validateShortID(verr, *r.ShortID)
+	}
+	validateLinkOptions(verr, r.ExpireAtDatetime, r.ExpireAtViews, r.Description, r.Password, r.Tags, r.Pixels, r.Meta)
+	return verr.err()
+}
+
+func validateLongURL(verr *ValidationError, longURL string) {
+	if strings.TrimSpace(longURL) == "" {
+		verr.Add("long_url", "The long url field is required.")
+		return
+	}
+	if err := validateHTTPURL(longURL); err != nil {
+		verr.Add("long_url", err.Error())
+	}
+}
+
+func validateShortID(verr *ValidationError, shortID string) {
+	switch {
+	case shortID == "":
+		verr.Add("short_id", "short_id must not be empty")
+	case len(shortID) > maxShortIDLength:
+		verr.Add("short_id", fmt.Sprintf("short_id must be at most %d characters", maxShortIDLength))
+	case !shortIDPattern.MatchString(shortID):
+		verr.Add("short_id", "short_id may only contain letters, numbers, dashes and underscores")
+	}
+}
+
+// validateDomain accepts either a bare hostname ("t.ly") or a domain URL
+// as used by the API ("https://t.ly/").
+func validateDomain(verr *ValidationError, domain string) {
+	host := domain
+	if strings.Contains(domain, "://") {
+		parsed, err := url.Parse(domain)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			verr.Add("domain", fmt.Sprintf("invalid domain %q", domain))
+			return
+		}
+		if strings.Trim(parsed.Path, "/") != "" || parsed.RawQuery != "" {
+			verr.Add("domain", fmt.Sprintf("domain %q must not contain a path or query", domain))
+			return
+		}
+		host = parsed.Hostname()
+	}
+	if !hostnamePattern.MatchString(host) {
+		verr.Add("domain", fmt.Sprintf("invalid domain %q", domain))
+	}
+}
+
+func validateLinkOptions(verr *ValidationError, expireAt *string, expireViews *int, description, password *string, tags, pixels []int, meta interface{}) {
+	if expireAt != nil {
+		if t, err := parseAPIDateTime(*expireAt); err != nil {
+			verr.Add("expire_at_datetime", err.Error())
+		} else if !t.After(time.Now()) {
+			verr.Add("expire_at_datetime", "expire_at_datetime must be in the future")
+		}
+	}
+	if expireViews != nil && *expireViews <= 0 {
+		verr.Add("expire_at_views", "expire_at_views must be positive")
+	}
+	if description != nil && len([]rune(*description)) > maxDescriptionLength {
+		verr.Add("description", fmt.Sprintf("description must be at most %d characters", maxDescriptionLength))
+	}
+	if password != nil {
+		if *password == "" {
+			verr.Add("password", "password must not be empty")
+		} else if len([]rune(*password)) > maxPasswordLength {
+			verr.Add("password", fmt.Sprintf("password must be at most %d characters", maxPasswordLength))
+		}
+	}
+	if id, ok := firstDuplicate(tags); ok {
+		verr.Add("tags", fmt.Sprintf("duplicate tag id %d", id))
+	}
+	if id, ok := firstDuplicate(pixels); ok {
+		verr.Add("pixels", fmt.Sprintf("duplicate pixel id %d", id))
+	}
+
+	var typedMeta *LinkMeta
+	switch m := meta.(type) {
+	case LinkMeta:
+		typedMeta = &m
+	case *LinkMeta:
+		typedMeta = m
+	}
+	if typedMeta != nil {
+		if err := typedMeta.Validate(); err != nil {
+			verr.Add("meta", err.Error())
+		}
+	}
+}
+
+func firstDuplicate(ids []int) (int, bool) {
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return id, true
+		}
+		seen[id] = true
+	}
+	return 0, false
+}